@@ -0,0 +1,131 @@
+package queryalternatives
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+)
+
+// ParseAdminFile parses a file in the administrative directory of
+// update-alternatives (usually /var/lib/dpkg/alternatives/<name>) without
+// executing any command.
+//
+// The file does not record everything `update-alternatives --query` reports:
+//   - Name is left empty because it is the name of the file.
+//   - Best is computed as the alternative with the highest priority.
+//   - Value is set to Best in auto mode (or "none" without alternatives), and
+//     left empty in manual mode because the selection is only recorded by
+//     the symlink in /etc/alternatives.
+func ParseAdminFile(r io.Reader) (*Alternatives, error) {
+	scanner := bufio.NewScanner(r)
+	lineNo := 0
+	next := func() (string, error) {
+		if !scanner.Scan() {
+			if err := scanner.Err(); err != nil {
+				return "", err
+			}
+			return "", &ParseError{
+				Code:    CodeUnexpectedEOF,
+				Message: "unexpected end of file",
+				Line:    lineNo,
+			}
+		}
+		lineNo++
+		return scanner.Text(), nil
+	}
+
+	result := newAlternatives()
+
+	status, err := next()
+	if err != nil {
+		return nil, err
+	}
+	switch Status(status) {
+	case StatusAuto, StatusManual:
+		result.Status = Status(status)
+	default:
+		return nil, &ParseError{
+			Code:    CodeInvalidStatus,
+			Message: fmt.Sprintf("invalid status: %q", status),
+			Line:    lineNo,
+		}
+	}
+
+	if result.Link, err = next(); err != nil {
+		return nil, err
+	}
+
+	// Slave names and links, terminated by an empty line.
+	var slaveNames []string
+	for {
+		name, err := next()
+		if err != nil {
+			return nil, err
+		}
+		if name == "" {
+			break
+		}
+		link, err := next()
+		if err != nil {
+			return nil, err
+		}
+		slaveNames = append(slaveNames, name)
+		result.Slaves[name] = link
+	}
+
+	// Alternatives, terminated by an empty line.
+	var best *Alternative
+	for {
+		path, err := next()
+		if err != nil {
+			return nil, err
+		}
+		if path == "" {
+			break
+		}
+
+		alt := newAlternative()
+		alt.Path = path
+
+		priority, err := next()
+		if err != nil {
+			return nil, err
+		}
+		if alt.Priority, err = parsePriority(priority); err != nil {
+			return nil, &ParseError{
+				Code:    CodeInvalidPriority,
+				Message: "invalid priority value",
+				Line:    lineNo,
+			}
+		}
+
+		// One line for each slave, which is empty if the alternative does
+		// not provide the slave.
+		for _, name := range slaveNames {
+			target, err := next()
+			if err != nil {
+				return nil, err
+			}
+			if target != "" {
+				alt.Slaves[name] = target
+			}
+		}
+
+		result.Alternatives = append(result.Alternatives, *alt)
+		if best == nil || alt.Priority > best.Priority {
+			best = alt
+		}
+	}
+
+	if best != nil {
+		result.Best = best.Path
+	}
+	if result.Status == StatusAuto {
+		result.Value = result.Best
+		if result.Value == "" {
+			result.Value = "none"
+		}
+	}
+
+	return result, nil
+}
@@ -0,0 +1,135 @@
+package queryalternatives_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/kofuk/go-queryalternatives"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_ParseAdminFile(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		input    string
+		expected *queryalternatives.Alternatives
+	}{
+		{
+			name: "auto with slaves",
+			input: `auto
+/usr/bin/awk
+awk.1.gz
+/usr/share/man/man1/awk.1.gz
+nawk
+/usr/bin/nawk
+nawk.1.gz
+/usr/share/man/man1/nawk.1.gz
+
+/usr/bin/gawk
+10
+/usr/share/man/man1/gawk.1.gz
+/usr/bin/gawk
+
+/usr/bin/mawk
+5
+/usr/share/man/man1/mawk.1.gz
+/usr/bin/mawk
+/usr/share/man/man1/mawk.1.gz
+
+`,
+			expected: &queryalternatives.Alternatives{
+				Link: "/usr/bin/awk",
+				Slaves: map[string]string{
+					"awk.1.gz":  "/usr/share/man/man1/awk.1.gz",
+					"nawk":      "/usr/bin/nawk",
+					"nawk.1.gz": "/usr/share/man/man1/nawk.1.gz",
+				},
+				Status: queryalternatives.StatusAuto,
+				Best:   "/usr/bin/gawk",
+				Value:  "/usr/bin/gawk",
+				Alternatives: []queryalternatives.Alternative{
+					{
+						Path:     "/usr/bin/gawk",
+						Priority: 10,
+						Slaves: map[string]string{
+							"awk.1.gz": "/usr/share/man/man1/gawk.1.gz",
+							"nawk":     "/usr/bin/gawk",
+						},
+					},
+					{
+						Path:     "/usr/bin/mawk",
+						Priority: 5,
+						Slaves: map[string]string{
+							"awk.1.gz":  "/usr/share/man/man1/mawk.1.gz",
+							"nawk":      "/usr/bin/mawk",
+							"nawk.1.gz": "/usr/share/man/man1/mawk.1.gz",
+						},
+					},
+				},
+			},
+		},
+		{
+			name: "manual without slaves",
+			input: `manual
+/usr/bin/cc
+
+/usr/bin/gcc
+20
+
+`,
+			expected: &queryalternatives.Alternatives{
+				Link:   "/usr/bin/cc",
+				Slaves: map[string]string{},
+				Status: queryalternatives.StatusManual,
+				Best:   "/usr/bin/gcc",
+				Alternatives: []queryalternatives.Alternative{
+					{
+						Path:     "/usr/bin/gcc",
+						Priority: 20,
+						Slaves:   map[string]string{},
+					},
+				},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			result, err := queryalternatives.ParseAdminFile(strings.NewReader(test.input))
+			assert.NoError(t, err)
+			assert.Equal(t, test.expected, result)
+		})
+	}
+}
+
+func Test_ParseAdminFile_Error(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name         string
+		input        string
+		expectedLine int
+	}{
+		{name: "empty", input: "", expectedLine: 0},
+		{name: "invalid status", input: "sometimes\n/usr/bin/cc\n\n\n", expectedLine: 1},
+		{name: "invalid priority", input: "auto\n/usr/bin/cc\n\n/usr/bin/gcc\nhigh\n\n", expectedLine: 5},
+		{name: "truncated", input: "auto\n/usr/bin/awk\nawk.1.gz\n/usr/share/man/man1/awk.1.gz\n\n/usr/bin/mawk\n5\n", expectedLine: 7},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			result, err := queryalternatives.ParseAdminFile(strings.NewReader(test.input))
+			assert.Nil(t, result)
+			var parseErr *queryalternatives.ParseError
+			if assert.ErrorAs(t, err, &parseErr) {
+				assert.Equal(t, test.expectedLine, parseErr.Line)
+			}
+		})
+	}
+}
@@ -0,0 +1,183 @@
+package queryalternatives
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os/exec"
+	"time"
+)
+
+// Set executes `update-alternatives --set name path`, which selects path
+// and switches the group to manual mode.
+// If q.DryRun is set, nothing is executed.
+func (q *Querier) Set(ctx context.Context, name, path string) (err error) {
+	if q.DryRun {
+		return nil
+	}
+	defer q.complete("--set", time.Now(), &err)
+
+	_, err = q.run(ctx, "--set", name, path)
+	return err
+}
+
+// Auto executes `update-alternatives --auto name`, which switches the group
+// to automatic mode.
+// If q.DryRun is set, nothing is executed.
+func (q *Querier) Auto(ctx context.Context, name string) (err error) {
+	if q.DryRun {
+		return nil
+	}
+	defer q.complete("--auto", time.Now(), &err)
+
+	_, err = q.run(ctx, "--auto", name)
+	return err
+}
+
+// satisfies reports whether a is already in the desired state.
+// Only Mode and, in manual mode, Current of desired are considered.
+func (a *Alternatives) satisfies(desired Selection) bool {
+	if a.Status != desired.Mode {
+		return false
+	}
+	return desired.Mode != StatusManual || a.Value == desired.Current
+}
+
+// Apply converges the group desired.Name to the desired selection.
+// In auto mode, only Mode is used. In manual mode, Current is selected.
+// The current state is read first, and the group is only modified when it
+// differs from desired, so changed is false if nothing had to be done.
+// After a modification the state is read again to confirm it converged.
+// If q.DryRun is set, Apply only reports whether a change would be made.
+func (q *Querier) Apply(ctx context.Context, desired Selection) (changed bool, err error) {
+	if desired.Mode != StatusAuto && desired.Mode != StatusManual {
+		return false, fmt.Errorf("%s: invalid mode: %q", desired.Name, desired.Mode)
+	}
+	if desired.Mode == StatusManual && desired.Current == "" {
+		return false, fmt.Errorf("%s: no path to select in manual mode", desired.Name)
+	}
+
+	current, err := q.Query(ctx, desired.Name)
+	if err != nil {
+		return false, err
+	}
+	if current.satisfies(desired) {
+		return false, nil
+	}
+	if q.DryRun {
+		return true, nil
+	}
+
+	if desired.Mode == StatusAuto {
+		err = q.Auto(ctx, desired.Name)
+	} else {
+		err = q.Set(ctx, desired.Name, desired.Current)
+	}
+	if err != nil {
+		return false, err
+	}
+
+	current, err = q.Query(ctx, desired.Name)
+	if err != nil {
+		return true, err
+	}
+	if !current.satisfies(desired) {
+		return true, fmt.Errorf("%s: did not converge to the desired selection", desired.Name)
+	}
+	return true, nil
+}
+
+// Apply converges a group to the desired selection using the Querier
+// carried by ctx (see WithQuerier).
+// See Querier.Apply for details.
+func Apply(ctx context.Context, desired Selection) (changed bool, err error) {
+	return querierFrom(ctx).Apply(ctx, desired)
+}
+
+// ApplyResult is the outcome of applying the desired selection of a single
+// group.
+type ApplyResult struct {
+	// Name is the name of the group.
+	Name string
+	// Changed reports whether the group was modified.
+	Changed bool
+	// Err is the error which occurred for this group, if any.
+	Err error
+}
+
+// ApplyAll applies each of desired in order with Apply and returns a result
+// for each of them. An error for a group is recorded in its result and does
+// not prevent the remaining groups from being applied.
+// The returned error is only non-nil if applying cannot continue at all,
+// such as when ctx is done or update-alternatives cannot be executed; the
+// results for the groups applied so far are returned along with it.
+// If q is nil, the Querier carried by ctx is used (see WithQuerier).
+func ApplyAll(ctx context.Context, q *Querier, desired []Selection) ([]ApplyResult, error) {
+	if q == nil {
+		q = querierFrom(ctx)
+	}
+
+	results := make([]ApplyResult, 0, len(desired))
+	for _, d := range desired {
+		if err := ctx.Err(); err != nil {
+			return results, err
+		}
+
+		changed, err := q.Apply(ctx, d)
+		if isStartError(err) {
+			return results, err
+		}
+		results = append(results, ApplyResult{Name: d.Name, Changed: changed, Err: err})
+	}
+	return results, nil
+}
+
+// isStartError reports whether err means the command could not be started
+// at all, for example because the executable does not exist.
+func isStartError(err error) bool {
+	var execErr *exec.Error
+	var pathErr *fs.PathError
+	return errors.As(err, &execErr) || errors.As(err, &pathErr)
+}
+
+// WaitForSelection polls the group name every poll interval until path is
+// selected or ctx is done.
+// If ctx is done before that, the error of the last query is returned if it
+// failed, or ctx.Err() otherwise. A query which failed because ctx was done
+// while it was running does not count as the last query.
+// It fails if poll is not positive.
+func (q *Querier) WaitForSelection(ctx context.Context, name, path string, poll time.Duration) error {
+	if poll <= 0 {
+		return fmt.Errorf("%s: non-positive poll interval: %v", name, poll)
+	}
+	ticker := time.NewTicker(poll)
+	defer ticker.Stop()
+
+	var lastErr error
+	for {
+		alts, err := q.Query(ctx, name)
+		if err == nil && alts.Value == path {
+			return nil
+		}
+		if err == nil || ctx.Err() == nil {
+			lastErr = err
+		}
+
+		select {
+		case <-ctx.Done():
+			if lastErr != nil {
+				return lastErr
+			}
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// WaitForSelection polls the group name until path is selected using the
+// Querier carried by ctx (see WithQuerier).
+// See Querier.WaitForSelection for details.
+func WaitForSelection(ctx context.Context, name, path string, poll time.Duration) error {
+	return querierFrom(ctx).WaitForSelection(ctx, name, path, poll)
+}
@@ -0,0 +1,319 @@
+package queryalternatives_test
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os/exec"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/kofuk/go-queryalternatives"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeSystem simulates update-alternatives with mutable state.
+type fakeSystem struct {
+	mu     sync.Mutex
+	groups map[string]*queryalternatives.Alternatives
+	writes int
+}
+
+func newFakeSystem(groups ...*queryalternatives.Alternatives) *fakeSystem {
+	s := &fakeSystem{groups: make(map[string]*queryalternatives.Alternatives)}
+	for _, g := range groups {
+		s.groups[g.Name] = g
+	}
+	return s
+}
+
+func (s *fakeSystem) best(g *queryalternatives.Alternatives) string {
+	best := "none"
+	priority := 0
+	for _, alt := range g.Alternatives {
+		if best == "none" || alt.Priority > priority {
+			best = alt.Path
+			priority = alt.Priority
+		}
+	}
+	return best
+}
+
+func (s *fakeSystem) Run(cmd *exec.Cmd) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	args := cmd.Args[1:]
+	notFound := func(name string) error {
+		fmt.Fprintf(cmd.Stderr, "update-alternatives: error: no alternatives for %s\n", name)
+		return exitError(2)
+	}
+
+	switch {
+	case len(args) == 2 && args[0] == "--query":
+		g, ok := s.groups[args[1]]
+		if !ok {
+			return notFound(args[1])
+		}
+		fmt.Fprintf(cmd.Stdout, "Name: %s\nLink: %s\nStatus: %s\nBest: %s\nValue: %s\n",
+			g.Name, g.Link, g.Status, g.Best, g.Value)
+		for _, alt := range g.Alternatives {
+			fmt.Fprintf(cmd.Stdout, "\nAlternative: %s\nPriority: %d\n", alt.Path, alt.Priority)
+		}
+		return nil
+	case len(args) == 1 && args[0] == "--get-selections":
+		for _, g := range s.groups {
+			fmt.Fprintf(cmd.Stdout, "%-30s %-8s %s\n", g.Name, g.Status, g.Value)
+		}
+		return nil
+	case len(args) == 3 && args[0] == "--set":
+		g, ok := s.groups[args[1]]
+		if !ok {
+			return notFound(args[1])
+		}
+		for _, alt := range g.Alternatives {
+			if alt.Path == args[2] {
+				s.writes++
+				g.Status = queryalternatives.StatusManual
+				g.Value = args[2]
+				return nil
+			}
+		}
+		fmt.Fprintf(cmd.Stderr, "update-alternatives: error: alternative %s for %s not registered; not setting\n", args[2], args[1])
+		return exitError(2)
+	case len(args) == 2 && args[0] == "--auto":
+		g, ok := s.groups[args[1]]
+		if !ok {
+			return notFound(args[1])
+		}
+		s.writes++
+		g.Status = queryalternatives.StatusAuto
+		g.Value = s.best(g)
+		return nil
+	}
+
+	io.WriteString(cmd.Stderr, "update-alternatives: error: unknown argument: "+strings.Join(args, " ")+"\n")
+	return exitError(2)
+}
+
+func Test_Querier_Apply(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		desired queryalternatives.Selection
+	}{
+		{
+			name: "manual",
+			desired: queryalternatives.Selection{
+				Name:    "java",
+				Mode:    queryalternatives.StatusManual,
+				Current: "/usr/lib/jvm/java-8-openjdk-amd64/jre/bin/java",
+			},
+		},
+		{
+			name: "auto",
+			desired: queryalternatives.Selection{
+				Name: "editor",
+				Mode: queryalternatives.StatusAuto,
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			system := newFakeSystem(newJava(), newEditor())
+			q := &queryalternatives.Querier{Runner: system.Run}
+
+			changed, err := q.Apply(context.Background(), test.desired)
+			require.NoError(t, err)
+			assert.True(t, changed)
+			assert.Equal(t, 1, system.writes)
+
+			changed, err = q.Apply(context.Background(), test.desired)
+			require.NoError(t, err)
+			assert.False(t, changed)
+			assert.Equal(t, 1, system.writes)
+		})
+	}
+}
+
+func Test_Querier_Apply_Error(t *testing.T) {
+	t.Parallel()
+
+	system := newFakeSystem(newJava())
+	q := &queryalternatives.Querier{Runner: system.Run}
+
+	_, err := q.Apply(context.Background(), queryalternatives.Selection{
+		Name:    "java",
+		Mode:    queryalternatives.StatusManual,
+		Current: "/usr/bin/not-registered",
+	})
+	var queryErr *queryalternatives.QueryError
+	assert.ErrorAs(t, err, &queryErr)
+
+	_, err = q.Apply(context.Background(), queryalternatives.Selection{
+		Name: "nosuch",
+		Mode: queryalternatives.StatusAuto,
+	})
+	assert.ErrorIs(t, err, queryalternatives.ErrNotFound)
+
+	_, err = q.Apply(context.Background(), queryalternatives.Selection{
+		Name: "java",
+		Mode: queryalternatives.StatusManual,
+	})
+	assert.Error(t, err)
+	assert.Equal(t, 0, system.writes)
+}
+
+func Test_Querier_WaitForSelection(t *testing.T) {
+	t.Parallel()
+
+	system := newFakeSystem(newJava())
+	polls := 0
+	q := &queryalternatives.Querier{
+		Runner: func(cmd *exec.Cmd) error {
+			polls++
+			if polls == 3 {
+				system.groups["java"].Value = "/usr/lib/jvm/java-8-openjdk-amd64/jre/bin/java"
+			}
+			return system.Run(cmd)
+		},
+	}
+
+	err := q.WaitForSelection(context.Background(), "java", "/usr/lib/jvm/java-8-openjdk-amd64/jre/bin/java", time.Millisecond)
+	assert.NoError(t, err)
+	assert.Equal(t, 3, polls)
+}
+
+func Test_Querier_WaitForSelection_Timeout(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		group    string
+		expected error
+	}{
+		{
+			name:     "never selected",
+			group:    "java",
+			expected: context.DeadlineExceeded,
+		},
+		{
+			name:     "query keeps failing",
+			group:    "nosuch",
+			expected: queryalternatives.ErrNotFound,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			system := newFakeSystem(newJava())
+			q := &queryalternatives.Querier{Runner: system.Run}
+
+			ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+			defer cancel()
+
+			err := q.WaitForSelection(ctx, test.group, "/usr/lib/jvm/java-8-openjdk-amd64/jre/bin/java", time.Millisecond)
+			assert.ErrorIs(t, err, test.expected)
+		})
+	}
+}
+
+func Test_Querier_WaitForSelection_InvalidPoll(t *testing.T) {
+	t.Parallel()
+
+	system := newFakeSystem(newJava())
+	q := &queryalternatives.Querier{Runner: system.Run}
+
+	for _, poll := range []time.Duration{0, -time.Second} {
+		err := q.WaitForSelection(context.Background(), "java", "/usr/lib/jvm/java-21-openjdk-amd64/bin/java", poll)
+		assert.Error(t, err)
+	}
+}
+
+func Test_Querier_WaitForSelection_CanceledQuery(t *testing.T) {
+	t.Parallel()
+
+	sleep, err := exec.LookPath("sleep")
+	if err != nil {
+		t.Skip("sleep is not available")
+	}
+
+	system := newFakeSystem(newJava())
+	polls := 0
+	q := &queryalternatives.Querier{
+		Runner: func(cmd *exec.Cmd) error {
+			polls++
+			if polls == 1 {
+				return system.Run(cmd)
+			}
+			// Hang in place of update-alternatives until the process is
+			// killed when ctx is done.
+			cmd.Path = sleep
+			cmd.Args = []string{"sleep", "30"}
+			return cmd.Run()
+		},
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	err = q.WaitForSelection(ctx, "java", "/usr/lib/jvm/java-8-openjdk-amd64/jre/bin/java", time.Millisecond)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+}
+
+func Test_ApplyAll(t *testing.T) {
+	t.Parallel()
+
+	system := newFakeSystem(newJava(), newEditor())
+	q := &queryalternatives.Querier{Runner: system.Run}
+
+	results, err := queryalternatives.ApplyAll(context.Background(), q, []queryalternatives.Selection{
+		{Name: "java", Mode: queryalternatives.StatusAuto},
+		{Name: "editor", Mode: queryalternatives.StatusAuto},
+		{Name: "nosuch", Mode: queryalternatives.StatusAuto},
+		{Name: "java", Mode: queryalternatives.StatusManual, Current: "/usr/bin/not-registered"},
+	})
+	require.NoError(t, err)
+	require.Len(t, results, 4)
+
+	assert.Equal(t, queryalternatives.ApplyResult{Name: "java"}, results[0])
+	assert.Equal(t, queryalternatives.ApplyResult{Name: "editor", Changed: true}, results[1])
+	assert.Equal(t, "nosuch", results[2].Name)
+	assert.ErrorIs(t, results[2].Err, queryalternatives.ErrNotFound)
+	assert.Equal(t, "java", results[3].Name)
+	assert.False(t, results[3].Changed)
+	assert.Error(t, results[3].Err)
+	assert.Equal(t, 1, system.writes)
+}
+
+func Test_ApplyAll_Catastrophic(t *testing.T) {
+	t.Parallel()
+
+	desired := []queryalternatives.Selection{
+		{Name: "java", Mode: queryalternatives.StatusAuto},
+		{Name: "editor", Mode: queryalternatives.StatusAuto},
+	}
+
+	for _, path := range []string{"/nonexistent/update-alternatives", "nonexistent-update-alternatives"} {
+		q := &queryalternatives.Querier{Path: path}
+		results, err := queryalternatives.ApplyAll(context.Background(), q, desired)
+		assert.Error(t, err)
+		assert.Empty(t, results)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	system := newFakeSystem(newJava(), newEditor())
+	results, err := queryalternatives.ApplyAll(ctx, &queryalternatives.Querier{Runner: system.Run}, desired)
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.Empty(t, results)
+}
@@ -0,0 +1,136 @@
+package queryalternatives
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// CachingQuerier caches the results of Querier.Query for a fixed duration.
+// It is safe for concurrent use. Concurrent queries for the same name which
+// miss the cache are coalesced into a single command execution.
+// Errors are not cached. A caller whose context is done stops waiting, but
+// the query keeps running for the other callers and the cache; it is
+// canceled when every caller waiting for it has stopped waiting.
+//
+// An expired result is removed when its name is queried again. Results for
+// names which are never queried again are kept until Invalidate is called.
+//
+// The returned *Alternatives are shared between callers and must not be
+// modified.
+type CachingQuerier struct {
+	querier *Querier
+	ttl     time.Duration
+
+	mu      sync.Mutex
+	entries map[string]*cacheEntry
+}
+
+type cacheEntry struct {
+	// done is closed when result and err are available.
+	done    chan struct{}
+	result  *Alternatives
+	err     error
+	expires time.Time
+
+	// waiters is the number of callers waiting for done, and cancel
+	// cancels the query. They are guarded by CachingQuerier.mu.
+	waiters int
+	cancel  context.CancelFunc
+}
+
+// NewCachingQuerier returns a CachingQuerier which caches the results of q
+// for ttl. If q is nil, the default Querier is used.
+func NewCachingQuerier(q *Querier, ttl time.Duration) *CachingQuerier {
+	if q == nil {
+		q = defaultQuerier
+	}
+	return &CachingQuerier{
+		querier: q,
+		ttl:     ttl,
+		entries: make(map[string]*cacheEntry),
+	}
+}
+
+// Query returns the cached result for name if it is not older than the TTL,
+// or queries it otherwise.
+func (c *CachingQuerier) Query(ctx context.Context, name string) (*Alternatives, error) {
+	c.mu.Lock()
+	entry, ok := c.entries[name]
+	if ok {
+		select {
+		case <-entry.done:
+			if time.Now().After(entry.expires) {
+				delete(c.entries, name)
+				ok = false
+			}
+		default:
+			// Another goroutine is querying it.
+		}
+	}
+	if !ok {
+		// The query is shared by every caller waiting for entry, so it is
+		// not canceled when the context of the caller which started it is
+		// done, but when the last caller stops waiting.
+		queryCtx, cancel := context.WithCancel(context.WithoutCancel(ctx))
+		entry = &cacheEntry{done: make(chan struct{}), cancel: cancel}
+		c.entries[name] = entry
+		go c.fill(queryCtx, name, entry)
+	}
+	entry.waiters++
+	c.mu.Unlock()
+
+	select {
+	case <-entry.done:
+		c.leave(name, entry)
+		return entry.result, entry.err
+	case <-ctx.Done():
+		c.leave(name, entry)
+		return nil, ctx.Err()
+	}
+}
+
+// leave records that a caller stopped waiting for entry. If it was the last
+// one and the query is still running, the query is canceled and entry is
+// removed so that the next caller starts a new one.
+func (c *CachingQuerier) leave(name string, entry *cacheEntry) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry.waiters--
+	if entry.waiters > 0 {
+		return
+	}
+	select {
+	case <-entry.done:
+	default:
+		entry.cancel()
+		if c.entries[name] == entry {
+			delete(c.entries, name)
+		}
+	}
+}
+
+// fill queries name and stores the result in entry.
+func (c *CachingQuerier) fill(ctx context.Context, name string, entry *cacheEntry) {
+	defer entry.cancel()
+
+	entry.result, entry.err = c.querier.Query(ctx, name)
+	entry.expires = time.Now().Add(c.ttl)
+
+	if entry.err != nil {
+		c.mu.Lock()
+		if c.entries[name] == entry {
+			delete(c.entries, name)
+		}
+		c.mu.Unlock()
+	}
+	close(entry.done)
+}
+
+// Invalidate removes the cached result for name, if any.
+func (c *CachingQuerier) Invalidate(name string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.entries, name)
+}
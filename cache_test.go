@@ -0,0 +1,198 @@
+package queryalternatives_test
+
+import (
+	"context"
+	"os/exec"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/kofuk/go-queryalternatives"
+	"github.com/stretchr/testify/assert"
+)
+
+func countingRunner(calls *atomic.Int32, commands map[string]fakeCommand) func(cmd *exec.Cmd) error {
+	runner := fakeRunner(commands)
+	return func(cmd *exec.Cmd) error {
+		calls.Add(1)
+		// Give concurrent callers a chance to pile up.
+		time.Sleep(10 * time.Millisecond)
+		return runner(cmd)
+	}
+}
+
+func Test_CachingQuerier_Concurrent(t *testing.T) {
+	t.Parallel()
+
+	var calls atomic.Int32
+	q := &queryalternatives.Querier{
+		Runner: countingRunner(&calls, map[string]fakeCommand{
+			"--query java": {stdout: javaQuery},
+		}),
+	}
+	c := queryalternatives.NewCachingQuerier(q, time.Hour)
+
+	var wg sync.WaitGroup
+	for range 20 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			result, err := c.Query(context.Background(), "java")
+			assert.NoError(t, err)
+			assert.Equal(t, "java", result.Name)
+		}()
+	}
+	wg.Wait()
+
+	assert.Equal(t, int32(1), calls.Load())
+
+	c.Invalidate("java")
+	_, err := c.Query(context.Background(), "java")
+	assert.NoError(t, err)
+	assert.Equal(t, int32(2), calls.Load())
+}
+
+func Test_CachingQuerier_Expiry(t *testing.T) {
+	t.Parallel()
+
+	var calls atomic.Int32
+	q := &queryalternatives.Querier{
+		Runner: countingRunner(&calls, map[string]fakeCommand{
+			"--query java": {stdout: javaQuery},
+		}),
+	}
+	c := queryalternatives.NewCachingQuerier(q, time.Millisecond)
+
+	_, err := c.Query(context.Background(), "java")
+	assert.NoError(t, err)
+	time.Sleep(5 * time.Millisecond)
+	_, err = c.Query(context.Background(), "java")
+	assert.NoError(t, err)
+
+	assert.Equal(t, int32(2), calls.Load())
+}
+
+func Test_CachingQuerier_ErrorNotCached(t *testing.T) {
+	t.Parallel()
+
+	var calls atomic.Int32
+	q := &queryalternatives.Querier{
+		Runner: countingRunner(&calls, map[string]fakeCommand{
+			"--query nosuch": {
+				stderr: "update-alternatives: error: no alternatives for nosuch\n",
+				exit:   2,
+			},
+		}),
+	}
+	c := queryalternatives.NewCachingQuerier(q, time.Hour)
+
+	for range 2 {
+		_, err := c.Query(context.Background(), "nosuch")
+		assert.ErrorIs(t, err, queryalternatives.ErrNotFound)
+	}
+	assert.Equal(t, int32(2), calls.Load())
+}
+
+func Test_CachingQuerier_FirstCallerCanceled(t *testing.T) {
+	t.Parallel()
+
+	started := make(chan struct{})
+	release := make(chan struct{})
+	runner := fakeRunner(map[string]fakeCommand{
+		"--query java": {stdout: javaQuery},
+	})
+	q := &queryalternatives.Querier{
+		Runner: func(cmd *exec.Cmd) error {
+			close(started)
+			<-release
+			return runner(cmd)
+		},
+	}
+	c := queryalternatives.NewCachingQuerier(q, time.Hour)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	firstErr := make(chan error)
+	go func() {
+		_, err := c.Query(ctx, "java")
+		firstErr <- err
+	}()
+	<-started
+
+	type reply struct {
+		result *queryalternatives.Alternatives
+		err    error
+	}
+	waiter := make(chan reply)
+	go func() {
+		result, err := c.Query(context.Background(), "java")
+		waiter <- reply{result, err}
+	}()
+	assert.Eventually(t, func() bool {
+		return c.Waiters("java") == 2
+	}, 10*time.Second, time.Millisecond)
+
+	cancel()
+	assert.ErrorIs(t, <-firstErr, context.Canceled)
+
+	close(release)
+	r := <-waiter
+	assert.NoError(t, r.err)
+	if assert.NotNil(t, r.result) {
+		assert.Equal(t, "java", r.result.Name)
+	}
+}
+
+func Test_CachingQuerier_LastCallerCanceled(t *testing.T) {
+	t.Parallel()
+
+	sleep, err := exec.LookPath("sleep")
+	if err != nil {
+		t.Skip("sleep is not available")
+	}
+
+	var calls atomic.Int32
+	started := make(chan struct{}, 2)
+	finished := make(chan error, 2)
+	q := &queryalternatives.Querier{
+		Runner: func(cmd *exec.Cmd) error {
+			calls.Add(1)
+			// Run a command which hangs in place of update-alternatives,
+			// so that only the context can stop it.
+			cmd.Path = sleep
+			cmd.Args = []string{"sleep", "30"}
+			started <- struct{}{}
+			err := cmd.Run()
+			finished <- err
+			return err
+		},
+	}
+	c := queryalternatives.NewCachingQuerier(q, time.Hour)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	firstErr := make(chan error)
+	go func() {
+		_, err := c.Query(ctx, "java")
+		firstErr <- err
+	}()
+	<-started
+	cancel()
+	assert.ErrorIs(t, <-firstErr, context.Canceled)
+
+	select {
+	case err := <-finished:
+		assert.Error(t, err)
+	case <-time.After(10 * time.Second):
+		t.Fatal("the query was not canceled after the last caller left")
+	}
+
+	// The next caller starts a new query instead of waiting for the
+	// canceled one.
+	ctx, cancel = context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	_, err = c.Query(ctx, "java")
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Eventually(t, func() bool {
+		return calls.Load() == 2
+	}, 10*time.Second, time.Millisecond)
+}
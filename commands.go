@@ -0,0 +1,78 @@
+package queryalternatives
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// InstallArgs returns, for each alternative, the arguments to
+// update-alternatives (without the program name) which register it with
+// `--install`, including a `--slave` option for each of its slaves.
+// Replaying them on another host recreates the group.
+// It fails if the group has no name or link, or if an alternative has a
+// slave which is not declared in the group-level Slaves.
+func (a *Alternatives) InstallArgs() ([][]string, error) {
+	if a.Name == "" {
+		return nil, errors.New("missing name")
+	}
+	if a.Link == "" {
+		return nil, fmt.Errorf("%s: missing link", a.Name)
+	}
+
+	result := make([][]string, 0, len(a.Alternatives))
+	for _, alt := range a.Alternatives {
+		if alt.Path == "" {
+			return nil, fmt.Errorf("%s: alternative with empty path", a.Name)
+		}
+		args := []string{"--install", a.Link, a.Name, alt.Path, strconv.Itoa(alt.Priority)}
+		for _, slave := range slaveLinks(alt.Slaves) {
+			link, ok := a.Slaves[slave.Link]
+			if !ok {
+				return nil, fmt.Errorf("%s: %s: slave %s is not declared in the group", a.Name, alt.Path, slave.Link)
+			}
+			args = append(args, "--slave", link, slave.Link, slave.Path)
+		}
+		result = append(result, args)
+	}
+	return result, nil
+}
+
+// SetCommand returns a shell command line which reproduces the current
+// selection of the group on another host: `update-alternatives --auto NAME`
+// in auto mode, or `update-alternatives --set NAME VALUE` in manual mode.
+// Arguments are quoted for POSIX shells where necessary.
+// The second result is false, and the command is empty, if there is no
+// meaningful command, such as when no alternative is selected.
+func (a *Alternatives) SetCommand() (string, bool) {
+	if a.Name == "" || isNone(a.Value) {
+		return "", false
+	}
+
+	var args []string
+	switch a.Status {
+	case StatusAuto:
+		args = []string{"update-alternatives", "--auto", a.Name}
+	case StatusManual:
+		args = []string{"update-alternatives", "--set", a.Name, a.Value}
+	default:
+		return "", false
+	}
+
+	for i, arg := range args {
+		args[i] = shellQuote(arg)
+	}
+	return strings.Join(args, " "), true
+}
+
+// shellQuote quotes s for POSIX shells if it contains characters which
+// would otherwise be interpreted by the shell.
+func shellQuote(s string) string {
+	if s != "" && strings.IndexFunc(s, func(r rune) bool {
+		return !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || strings.ContainsRune("-_./+:=@%,", r))
+	}) < 0 {
+		return s
+	}
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
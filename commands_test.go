@@ -0,0 +1,96 @@
+package queryalternatives_test
+
+import (
+	"testing"
+
+	"github.com/kofuk/go-queryalternatives"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_InstallArgs(t *testing.T) {
+	t.Parallel()
+
+	a := newJava()
+	a.Slaves["jexec"] = "/usr/bin/jexec"
+	a.Alternatives[0].Slaves["jexec"] = "/usr/lib/jvm/java-21-openjdk-amd64/lib/jexec"
+
+	result, err := a.InstallArgs()
+	assert.NoError(t, err)
+	assert.Equal(t, [][]string{
+		{
+			"--install", "/usr/bin/java", "java", "/usr/lib/jvm/java-21-openjdk-amd64/bin/java", "2111",
+			"--slave", "/usr/share/man/man1/java.1.gz", "java.1.gz", "/usr/lib/jvm/java-21-openjdk-amd64/man/man1/java.1.gz",
+			"--slave", "/usr/bin/jexec", "jexec", "/usr/lib/jvm/java-21-openjdk-amd64/lib/jexec",
+		},
+		{
+			"--install", "/usr/bin/java", "java", "/usr/lib/jvm/java-8-openjdk-amd64/jre/bin/java", "1081",
+			"--slave", "/usr/share/man/man1/java.1.gz", "java.1.gz", "/usr/lib/jvm/java-8-openjdk-amd64/jre/man/man1/java.1.gz",
+		},
+	}, result)
+}
+
+func Test_InstallArgs_Error(t *testing.T) {
+	t.Parallel()
+
+	noName := newJava()
+	noName.Name = ""
+	noLink := newJava()
+	noLink.Link = ""
+	undeclared := newJava()
+	undeclared.Alternatives[1].Slaves["jexec"] = "/usr/lib/jvm/java-8-openjdk-amd64/jre/lib/jexec"
+
+	tests := []struct {
+		name  string
+		input *queryalternatives.Alternatives
+	}{
+		{name: "missing name", input: noName},
+		{name: "missing link", input: noLink},
+		{name: "undeclared slave", input: undeclared},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			result, err := test.input.InstallArgs()
+			assert.Error(t, err)
+			assert.Nil(t, result)
+		})
+	}
+}
+
+func Test_SetCommand(t *testing.T) {
+	t.Parallel()
+
+	auto := newJava()
+	manual := newEditor()
+	autoNone := newJava()
+	autoNone.Value = "none"
+	manualNone := newEditor()
+	manualNone.Value = "none"
+	quoted := newEditor()
+	quoted.Value = "/opt/my editor/bin/it's"
+
+	tests := []struct {
+		name     string
+		input    *queryalternatives.Alternatives
+		expected string
+		ok       bool
+	}{
+		{name: "auto", input: auto, expected: "update-alternatives --auto java", ok: true},
+		{name: "manual", input: manual, expected: "update-alternatives --set editor /bin/nano", ok: true},
+		{name: "auto without selection", input: autoNone},
+		{name: "manual without selection", input: manualNone},
+		{name: "quoted", input: quoted, expected: `update-alternatives --set editor '/opt/my editor/bin/it'\''s'`, ok: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			result, ok := test.input.SetCommand()
+			assert.Equal(t, test.expected, result)
+			assert.Equal(t, test.ok, ok)
+		})
+	}
+}
@@ -0,0 +1,269 @@
+package queryalternatives
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+)
+
+// ChangeKind is the kind of a Change.
+type ChangeKind string
+
+const (
+	// ChangeLink means the generic link of the group changed.
+	ChangeLink ChangeKind = "link"
+	// ChangeStatus means the status of the group changed.
+	ChangeStatus ChangeKind = "status"
+	// ChangeBest means the best alternative of the group changed.
+	ChangeBest ChangeKind = "best"
+	// ChangeValue means the selected alternative of the group changed.
+	ChangeValue ChangeKind = "value"
+	// ChangeSlave means a group-level slave was added, removed or changed.
+	ChangeSlave ChangeKind = "slave"
+	// ChangeAlternativeAdded means a candidate was added.
+	ChangeAlternativeAdded ChangeKind = "alt-added"
+	// ChangeAlternativeRemoved means a candidate was removed.
+	ChangeAlternativeRemoved ChangeKind = "alt-removed"
+	// ChangePriority means the priority of a candidate changed.
+	ChangePriority ChangeKind = "priority"
+	// ChangeAlternativeSlave means a slave of a candidate was added, removed
+	// or changed.
+	ChangeAlternativeSlave ChangeKind = "alt-slave"
+)
+
+// Change is a single difference between two alternatives groups.
+type Change struct {
+	// Kind is the kind of the change.
+	Kind ChangeKind
+	// Name is the name of the group.
+	Name string
+	// Path is the path of the candidate for candidate-level changes.
+	Path string
+	// Slave is the slave link name for slave changes.
+	Slave string
+	// Old is the value before the change, or empty if it was absent.
+	Old string
+	// New is the value after the change, or empty if it was removed.
+	New string
+}
+
+// Key returns a stable identifier of what changed, such as "java:value",
+// "java:alt-added:/usr/bin/java-21" or "java:alt-slave:/usr/bin/java-21:java.1.gz",
+// which is useful to deduplicate notifications about the same drift.
+// It is made of Name, Kind, and Path and Slave if they are set; Old and New
+// are not part of the key.
+func (c Change) Key() string {
+	key := c.Name + ":" + string(c.Kind)
+	if c.Path != "" {
+		key += ":" + c.Path
+	}
+	if c.Slave != "" {
+		key += ":" + c.Slave
+	}
+	return key
+}
+
+// Diff returns the changes which turn a into b.
+// The changes are ordered deterministically: group-level fields first,
+// then group-level slaves, then candidates sorted by path.
+func Diff(a, b *Alternatives) []Change {
+	changes := make([]Change, 0)
+	add := func(c Change) {
+		c.Name = b.Name
+		changes = append(changes, c)
+	}
+
+	if a.Link != b.Link {
+		add(Change{Kind: ChangeLink, Old: a.Link, New: b.Link})
+	}
+	if a.statusText() != b.statusText() {
+		add(Change{Kind: ChangeStatus, Old: a.statusText(), New: b.statusText()})
+	}
+	if a.Best != b.Best {
+		add(Change{Kind: ChangeBest, Old: a.Best, New: b.Best})
+	}
+	if a.Value != b.Value {
+		add(Change{Kind: ChangeValue, Old: a.Value, New: b.Value})
+	}
+	for _, c := range diffSlaves(a.Slaves, b.Slaves) {
+		c.Kind = ChangeSlave
+		add(c)
+	}
+
+	oldAlts := make(map[string]Alternative, len(a.Alternatives))
+	for _, alt := range a.Alternatives {
+		oldAlts[alt.Path] = alt
+	}
+	newAlts := make(map[string]Alternative, len(b.Alternatives))
+	for _, alt := range b.Alternatives {
+		newAlts[alt.Path] = alt
+	}
+
+	for _, path := range unionKeys(oldAlts, newAlts) {
+		oldAlt, inOld := oldAlts[path]
+		newAlt, inNew := newAlts[path]
+		switch {
+		case !inNew:
+			add(Change{Kind: ChangeAlternativeRemoved, Path: path})
+		case !inOld:
+			add(Change{Kind: ChangeAlternativeAdded, Path: path})
+		default:
+			if oldAlt.Priority != newAlt.Priority {
+				add(Change{
+					Kind: ChangePriority,
+					Path: path,
+					Old:  strconv.Itoa(oldAlt.Priority),
+					New:  strconv.Itoa(newAlt.Priority),
+				})
+			}
+			for _, c := range diffSlaves(oldAlt.Slaves, newAlt.Slaves) {
+				c.Kind = ChangeAlternativeSlave
+				c.Path = path
+				add(c)
+			}
+		}
+	}
+
+	return changes
+}
+
+// PatchFrom returns the changes of the selection which turn baseline into a,
+// which is narrower than Diff: only Status, and Value in manual mode, are
+// compared. In auto mode Value follows the priorities, so a difference in
+// Value alone is not actionable and is not reported. Changes of slaves,
+// candidates and priorities are ignored.
+// It fails if the groups have different names.
+func (a *Alternatives) PatchFrom(baseline *Alternatives) ([]Change, error) {
+	if a.Name != baseline.Name {
+		return nil, fmt.Errorf("group name mismatch: %s != %s", baseline.Name, a.Name)
+	}
+
+	changes := make([]Change, 0)
+	if baseline.Status != a.Status {
+		changes = append(changes, Change{Kind: ChangeStatus, Name: a.Name, Old: string(baseline.Status), New: string(a.Status)})
+	}
+	if a.Status == StatusManual && baseline.Value != a.Value {
+		changes = append(changes, Change{Kind: ChangeValue, Name: a.Name, Old: baseline.Value, New: a.Value})
+	}
+	return changes, nil
+}
+
+// EqualPathsOnly reports whether a and b have the same name, link, status
+// and selected value, and the same set of candidate paths. Priorities,
+// the best alternative and slaves are ignored, since they may differ across
+// package versions without any meaningful change.
+func (a *Alternatives) EqualPathsOnly(b *Alternatives) bool {
+	if a.Name != b.Name || a.Link != b.Link || a.statusText() != b.statusText() || a.Value != b.Value {
+		return false
+	}
+
+	paths := func(alts []Alternative) map[string]struct{} {
+		result := make(map[string]struct{}, len(alts))
+		for _, alt := range alts {
+			result[alt.Path] = struct{}{}
+		}
+		return result
+	}
+	pa, pb := paths(a.Alternatives), paths(b.Alternatives)
+	if len(pa) != len(pb) {
+		return false
+	}
+	for path := range pa {
+		if _, ok := pb[path]; !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// diffSlaves returns the changes between two slave maps sorted by link name.
+// Only Slave, Old and New are set.
+func diffSlaves(a, b map[string]string) []Change {
+	var changes []Change
+	for _, link := range unionKeys(a, b) {
+		if a[link] != b[link] {
+			changes = append(changes, Change{Slave: link, Old: a[link], New: b[link]})
+		}
+	}
+	return changes
+}
+
+// unionKeys returns the sorted union of the keys of a and b.
+func unionKeys[V any](a, b map[string]V) []string {
+	keys := make([]string, 0, len(a)+len(b))
+	for k := range a {
+		keys = append(keys, k)
+	}
+	for k := range b {
+		if _, ok := a[k]; !ok {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// GroupDiff is the list of changes of a single group.
+type GroupDiff struct {
+	Name    string
+	Changes []Change
+}
+
+// InventoryDiff is the difference between two inventories.
+type InventoryDiff struct {
+	// OnlyA is the sorted names of groups which only exist in A.
+	OnlyA []string
+	// OnlyB is the sorted names of groups which only exist in B.
+	OnlyB []string
+	// Changed is the groups which exist in both but differ, sorted by name.
+	Changed []GroupDiff
+}
+
+// DiffInventories compares two inventories, for example those of two hosts.
+func DiffInventories(a, b []*Alternatives) InventoryDiff {
+	indexA := Index(a)
+	indexB := Index(b)
+
+	result := InventoryDiff{
+		OnlyA:   make([]string, 0),
+		OnlyB:   make([]string, 0),
+		Changed: make([]GroupDiff, 0),
+	}
+	for _, name := range unionKeys(indexA, indexB) {
+		groupA, inA := indexA[name]
+		groupB, inB := indexB[name]
+		switch {
+		case !inB:
+			result.OnlyA = append(result.OnlyA, name)
+		case !inA:
+			result.OnlyB = append(result.OnlyB, name)
+		default:
+			if changes := Diff(groupA, groupB); len(changes) > 0 {
+				result.Changed = append(result.Changed, GroupDiff{Name: name, Changes: changes})
+			}
+		}
+	}
+	return result
+}
+
+// InventoriesConverged reports whether a and b contain the same groups with
+// the same selections, that is, the same mode and selected alternative.
+// Priorities, slaves and the best alternatives are ignored.
+func InventoriesConverged(a, b []*Alternatives) bool {
+	indexA := Index(a)
+	indexB := Index(b)
+	if len(indexA) != len(indexB) {
+		return false
+	}
+	for name, groupA := range indexA {
+		groupB, ok := indexB[name]
+		if !ok {
+			return false
+		}
+		selA, selB := groupA.AsSelection(), groupB.AsSelection()
+		if selA.Mode != selB.Mode || selA.Current != selB.Current {
+			return false
+		}
+	}
+	return true
+}
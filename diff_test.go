@@ -0,0 +1,362 @@
+package queryalternatives_test
+
+import (
+	"testing"
+
+	"github.com/kofuk/go-queryalternatives"
+	"github.com/stretchr/testify/assert"
+)
+
+func newJava() *queryalternatives.Alternatives {
+	return &queryalternatives.Alternatives{
+		Name: "java",
+		Link: "/usr/bin/java",
+		Slaves: map[string]string{
+			"java.1.gz": "/usr/share/man/man1/java.1.gz",
+		},
+		Status: queryalternatives.StatusAuto,
+		Best:   "/usr/lib/jvm/java-21-openjdk-amd64/bin/java",
+		Value:  "/usr/lib/jvm/java-21-openjdk-amd64/bin/java",
+		Alternatives: []queryalternatives.Alternative{
+			{
+				Path:     "/usr/lib/jvm/java-21-openjdk-amd64/bin/java",
+				Priority: 2111,
+				Slaves: map[string]string{
+					"java.1.gz": "/usr/lib/jvm/java-21-openjdk-amd64/man/man1/java.1.gz",
+				},
+			},
+			{
+				Path:     "/usr/lib/jvm/java-8-openjdk-amd64/jre/bin/java",
+				Priority: 1081,
+				Slaves: map[string]string{
+					"java.1.gz": "/usr/lib/jvm/java-8-openjdk-amd64/jre/man/man1/java.1.gz",
+				},
+			},
+		},
+	}
+}
+
+func newEditor() *queryalternatives.Alternatives {
+	return &queryalternatives.Alternatives{
+		Name:   "editor",
+		Link:   "/usr/bin/editor",
+		Slaves: map[string]string{},
+		Status: queryalternatives.StatusManual,
+		Best:   "/usr/bin/vim.basic",
+		Value:  "/bin/nano",
+		Alternatives: []queryalternatives.Alternative{
+			{Path: "/bin/nano", Priority: 40, Slaves: map[string]string{}},
+			{Path: "/usr/bin/vim.basic", Priority: 50, Slaves: map[string]string{}},
+		},
+	}
+}
+
+// assertEqualGroup asserts that expected and actual are equal, ignoring the
+// original order recorded by the parser or SortByPriority.
+func assertEqualGroup(t *testing.T, expected, actual *queryalternatives.Alternatives, msgAndArgs ...any) bool {
+	t.Helper()
+	return assert.Equal(t, queryalternatives.WithoutOriginalOrder(expected), queryalternatives.WithoutOriginalOrder(actual), msgAndArgs...)
+}
+
+func Test_Diff(t *testing.T) {
+	t.Parallel()
+
+	a := newJava()
+	assert.Empty(t, queryalternatives.Diff(a, newJava()))
+
+	b := newJava()
+	b.Status = queryalternatives.StatusManual
+	b.Value = "/usr/lib/jvm/java-8-openjdk-amd64/jre/bin/java"
+	b.Slaves["jexec"] = "/usr/bin/jexec"
+	b.Alternatives[0].Priority = 2112
+	b.Alternatives[0].Slaves["java.1.gz"] = "/tmp/java.1.gz"
+	b.Alternatives = append(b.Alternatives[:1], queryalternatives.Alternative{
+		Path:     "/usr/lib/jvm/java-17-openjdk-amd64/bin/java",
+		Priority: 1711,
+	})
+
+	assert.Equal(t, []queryalternatives.Change{
+		{Kind: queryalternatives.ChangeStatus, Name: "java", Old: "auto", New: "manual"},
+		{
+			Kind: queryalternatives.ChangeValue,
+			Name: "java",
+			Old:  "/usr/lib/jvm/java-21-openjdk-amd64/bin/java",
+			New:  "/usr/lib/jvm/java-8-openjdk-amd64/jre/bin/java",
+		},
+		{Kind: queryalternatives.ChangeSlave, Name: "java", Slave: "jexec", New: "/usr/bin/jexec"},
+		{
+			Kind: queryalternatives.ChangeAlternativeAdded,
+			Name: "java",
+			Path: "/usr/lib/jvm/java-17-openjdk-amd64/bin/java",
+		},
+		{
+			Kind: queryalternatives.ChangePriority,
+			Name: "java",
+			Path: "/usr/lib/jvm/java-21-openjdk-amd64/bin/java",
+			Old:  "2111",
+			New:  "2112",
+		},
+		{
+			Kind:  queryalternatives.ChangeAlternativeSlave,
+			Name:  "java",
+			Path:  "/usr/lib/jvm/java-21-openjdk-amd64/bin/java",
+			Slave: "java.1.gz",
+			Old:   "/usr/lib/jvm/java-21-openjdk-amd64/man/man1/java.1.gz",
+			New:   "/tmp/java.1.gz",
+		},
+		{
+			Kind: queryalternatives.ChangeAlternativeRemoved,
+			Name: "java",
+			Path: "/usr/lib/jvm/java-8-openjdk-amd64/jre/bin/java",
+		},
+	}, queryalternatives.Diff(a, b))
+}
+
+func Test_Diff_UnknownStatus(t *testing.T) {
+	t.Parallel()
+
+	a := newJava()
+	a.Status = queryalternatives.StatusUnknown
+	a.StatusRaw = "sideways"
+	b := newJava()
+	b.Status = queryalternatives.StatusUnknown
+	b.StatusRaw = "bogus"
+
+	assert.Equal(t, []queryalternatives.Change{
+		{Kind: queryalternatives.ChangeStatus, Name: "java", Old: "sideways", New: "bogus"},
+	}, queryalternatives.Diff(a, b))
+	assert.False(t, a.EqualPathsOnly(b))
+
+	b.StatusRaw = "sideways"
+	assert.Empty(t, queryalternatives.Diff(a, b))
+	assert.True(t, a.EqualPathsOnly(b))
+}
+
+func Test_Change_Key(t *testing.T) {
+	t.Parallel()
+
+	b := newJava()
+	b.Status = queryalternatives.StatusManual
+	b.Value = "/usr/lib/jvm/java-8-openjdk-amd64/jre/bin/java"
+	b.Slaves["jexec"] = "/usr/bin/jexec"
+	b.Alternatives[0].Priority = 2112
+	b.Alternatives[0].Slaves["java.1.gz"] = "/tmp/java.1.gz"
+	b.Alternatives = append(b.Alternatives[:1], queryalternatives.Alternative{
+		Path:     "/usr/lib/jvm/java-17-openjdk-amd64/bin/java",
+		Priority: 1711,
+	})
+
+	keys := make([]string, 0)
+	for _, c := range queryalternatives.Diff(newJava(), b) {
+		keys = append(keys, c.Key())
+	}
+	assert.Equal(t, []string{
+		"java:status",
+		"java:value",
+		"java:slave:jexec",
+		"java:alt-added:/usr/lib/jvm/java-17-openjdk-amd64/bin/java",
+		"java:priority:/usr/lib/jvm/java-21-openjdk-amd64/bin/java",
+		"java:alt-slave:/usr/lib/jvm/java-21-openjdk-amd64/bin/java:java.1.gz",
+		"java:alt-removed:/usr/lib/jvm/java-8-openjdk-amd64/jre/bin/java",
+	}, keys)
+
+	// Repeated alerts about the same drift share a key, whatever the values.
+	first := queryalternatives.Change{Kind: queryalternatives.ChangeValue, Name: "java", Old: "/a", New: "/b"}
+	again := queryalternatives.Change{Kind: queryalternatives.ChangeValue, Name: "java", Old: "/a", New: "/c"}
+	assert.Equal(t, first.Key(), again.Key())
+
+	other := first
+	other.Name = "javac"
+	assert.NotEqual(t, first.Key(), other.Key())
+}
+
+func Test_DiffInventories(t *testing.T) {
+	t.Parallel()
+
+	pager := &queryalternatives.Alternatives{Name: "pager"}
+	awk := &queryalternatives.Alternatives{Name: "awk"}
+
+	changedJava := newJava()
+	changedJava.Value = "/usr/lib/jvm/java-8-openjdk-amd64/jre/bin/java"
+
+	result := queryalternatives.DiffInventories(
+		[]*queryalternatives.Alternatives{pager, newJava(), newEditor()},
+		[]*queryalternatives.Alternatives{newEditor(), changedJava, awk},
+	)
+
+	assert.Equal(t, []string{"pager"}, result.OnlyA)
+	assert.Equal(t, []string{"awk"}, result.OnlyB)
+	assert.Equal(t, []queryalternatives.GroupDiff{
+		{
+			Name: "java",
+			Changes: []queryalternatives.Change{
+				{
+					Kind: queryalternatives.ChangeValue,
+					Name: "java",
+					Old:  "/usr/lib/jvm/java-21-openjdk-amd64/bin/java",
+					New:  "/usr/lib/jvm/java-8-openjdk-amd64/jre/bin/java",
+				},
+			},
+		},
+	}, result.Changed)
+}
+
+func Test_PatchFrom(t *testing.T) {
+	t.Parallel()
+
+	java8 := "/usr/lib/jvm/java-8-openjdk-amd64/jre/bin/java"
+	java21 := "/usr/lib/jvm/java-21-openjdk-amd64/bin/java"
+
+	manual8 := newJava()
+	manual8.Status = queryalternatives.StatusManual
+	manual8.Value = java8
+
+	manual21 := newJava()
+	manual21.Status = queryalternatives.StatusManual
+
+	drifted := newJava()
+	drifted.Value = java8
+
+	churned := newJava()
+	churned.Slaves["jexec"] = "/usr/bin/jexec"
+	churned.Alternatives[0].Priority = 1
+	churned.Alternatives[0].Slaves["java.1.gz"] = "/tmp/java.1.gz"
+
+	tests := []struct {
+		name     string
+		baseline *queryalternatives.Alternatives
+		target   *queryalternatives.Alternatives
+		expected []queryalternatives.Change
+	}{
+		{name: "same", baseline: newJava(), target: newJava(), expected: []queryalternatives.Change{}},
+		{
+			name:     "pin",
+			baseline: newJava(),
+			target:   manual8,
+			expected: []queryalternatives.Change{
+				{Kind: queryalternatives.ChangeStatus, Name: "java", Old: "auto", New: "manual"},
+				{Kind: queryalternatives.ChangeValue, Name: "java", Old: java21, New: java8},
+			},
+		},
+		{
+			name:     "pin to the current value",
+			baseline: newJava(),
+			target:   manual21,
+			expected: []queryalternatives.Change{
+				{Kind: queryalternatives.ChangeStatus, Name: "java", Old: "auto", New: "manual"},
+			},
+		},
+		{
+			name:     "repin",
+			baseline: manual21,
+			target:   manual8,
+			expected: []queryalternatives.Change{
+				{Kind: queryalternatives.ChangeValue, Name: "java", Old: java21, New: java8},
+			},
+		},
+		{
+			name:     "unpin",
+			baseline: manual8,
+			target:   newJava(),
+			expected: []queryalternatives.Change{
+				{Kind: queryalternatives.ChangeStatus, Name: "java", Old: "manual", New: "auto"},
+			},
+		},
+		{name: "value in auto mode", baseline: drifted, target: newJava(), expected: []queryalternatives.Change{}},
+		{name: "slave and priority churn", baseline: newJava(), target: churned, expected: []queryalternatives.Change{}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			result, err := test.target.PatchFrom(test.baseline)
+			assert.NoError(t, err)
+			assert.Equal(t, test.expected, result)
+		})
+	}
+
+	_, err := newJava().PatchFrom(newEditor())
+	assert.Error(t, err)
+}
+
+func Test_EqualPathsOnly(t *testing.T) {
+	t.Parallel()
+
+	priorities := newJava()
+	priorities.Alternatives[0].Priority = 2112
+	priorities.Alternatives[1].Priority = 1082
+	priorities.Best = ""
+	priorities.Alternatives[0].Slaves["jexec"] = "/usr/lib/jvm/java-21-openjdk-amd64/lib/jexec"
+	reordered := newJava()
+	reordered.Alternatives[0], reordered.Alternatives[1] = reordered.Alternatives[1], reordered.Alternatives[0]
+	added := newJava()
+	added.Alternatives = append(added.Alternatives, queryalternatives.Alternative{Path: "/usr/lib/jvm/java-17-openjdk-amd64/bin/java", Priority: 1711})
+	replaced := newJava()
+	replaced.Alternatives[1].Path = "/usr/lib/jvm/java-17-openjdk-amd64/bin/java"
+	manual := newJava()
+	manual.Status = queryalternatives.StatusManual
+	selected := newJava()
+	selected.Value = "/usr/lib/jvm/java-8-openjdk-amd64/jre/bin/java"
+
+	tests := []struct {
+		name     string
+		b        *queryalternatives.Alternatives
+		expected bool
+	}{
+		{name: "same", b: newJava(), expected: true},
+		{name: "only priorities differ", b: priorities, expected: true},
+		{name: "reordered", b: reordered, expected: true},
+		{name: "candidate added", b: added},
+		{name: "candidate replaced", b: replaced},
+		{name: "status differs", b: manual},
+		{name: "value differs", b: selected},
+		{name: "different group", b: newEditor()},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			assert.Equal(t, test.expected, newJava().EqualPathsOnly(test.b))
+			assert.Equal(t, test.expected, test.b.EqualPathsOnly(newJava()))
+		})
+	}
+}
+
+func Test_InventoriesConverged(t *testing.T) {
+	t.Parallel()
+
+	baseline := []*queryalternatives.Alternatives{newJava(), newEditor()}
+
+	reprioritized := newJava()
+	reprioritized.Alternatives[1].Priority = 3000
+	reprioritized.Best = reprioritized.Alternatives[1].Path
+	reprioritized.Slaves = map[string]string{}
+	switched := newEditor()
+	switched.Value = "/usr/bin/vim.basic"
+	auto := newEditor()
+	auto.Status = queryalternatives.StatusAuto
+
+	tests := []struct {
+		name     string
+		b        []*queryalternatives.Alternatives
+		expected bool
+	}{
+		{name: "same", b: []*queryalternatives.Alternatives{newEditor(), newJava()}, expected: true},
+		{name: "priorities and slaves differ", b: []*queryalternatives.Alternatives{reprioritized, newEditor()}, expected: true},
+		{name: "value differs", b: []*queryalternatives.Alternatives{newJava(), switched}},
+		{name: "mode differs", b: []*queryalternatives.Alternatives{newJava(), auto}},
+		{name: "group missing", b: []*queryalternatives.Alternatives{newJava()}},
+		{name: "extra group", b: []*queryalternatives.Alternatives{newJava(), newEditor(), {Name: "pager"}}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			assert.Equal(t, test.expected, queryalternatives.InventoriesConverged(baseline, test.b))
+			assert.Equal(t, test.expected, queryalternatives.InventoriesConverged(test.b, baseline))
+		})
+	}
+}
@@ -0,0 +1,188 @@
+package queryalternatives
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+)
+
+// ParseDisplay parses the output of `update-alternatives --display`, which
+// is meant for humans but is what some tools and caches record.
+// Prefer the output of --query where possible; in particular, --display does
+// not distinguish a slave that an alternative does not provide from one
+// whose line was lost.
+//
+// The priority of an alternative may be decorated, as in "priority 1081",
+// "(priority 1081)" or "(1081)".
+//
+// Labels are matched case-insensitively, and the following variations
+// printed by different versions of update-alternatives are accepted:
+//   - "NAME - auto mode" and "NAME - status is auto."
+//   - "link best version is PATH", "best version is PATH" and
+//     "Current 'best' version is 'PATH'.", which older versions print after
+//     the alternatives
+//   - "link currently points to PATH" and "currently points to PATH"
+//   - "link NAME is LINK", "link group NAME is LINK" and "link group: LINK"
+//   - "slave NAME is LINK" and "slave NAME: LINK"
+func ParseDisplay(r io.Reader) (*Alternatives, error) {
+	scanner := bufio.NewScanner(r)
+	lineNo := 0
+	malformed := func() error {
+		return &ParseError{
+			Code:    CodeMalformedLine,
+			Message: "malformed line",
+			Line:    lineNo,
+		}
+	}
+
+	result := newAlternatives()
+	var currentAlt *Alternative
+	for scanner.Scan() {
+		lineNo++
+		line := strings.TrimRight(scanner.Text(), "\r")
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+
+		if result.Name == "" {
+			// The first line is "NAME - MODE mode".
+			m := displayModeRe.FindStringSubmatch(line)
+			if m == nil {
+				name, mode, ok := strings.Cut(line, " - ")
+				if !ok || name == "" {
+					return nil, malformed()
+				}
+				return nil, &ParseError{
+					Code:    CodeInvalidStatus,
+					Message: fmt.Sprintf("invalid status: %q", mode),
+					Line:    lineNo,
+				}
+			}
+			result.Name = m[1]
+			result.Status = Status(strings.ToLower(m[2]))
+			continue
+		}
+
+		if m := displayBestRe.FindStringSubmatch(line); m != nil && !strings.HasPrefix(line, " ") {
+			// Older versions print the best version after the alternatives.
+			result.Best = unquoteDisplay(m[1])
+			continue
+		}
+
+		if !strings.HasPrefix(line, " ") {
+			// The beginning of an alternative: "PATH - priority PRIORITY".
+			i := strings.LastIndex(line, " - ")
+			if i < 0 {
+				return nil, malformed()
+			}
+			priority, err := parseDisplayPriority(line[i+len(" - "):])
+			if err != nil {
+				return nil, &ParseError{
+					Code:    CodeInvalidPriority,
+					Message: "invalid priority value",
+					Line:    lineNo,
+				}
+			}
+			if currentAlt != nil {
+				result.Alternatives = append(result.Alternatives, *currentAlt)
+			}
+			currentAlt = newAlternative()
+			currentAlt.Path = line[:i]
+			currentAlt.Priority = priority
+			continue
+		}
+
+		line = strings.TrimLeft(line, " ")
+		if currentAlt != nil {
+			// "slave NAME: PATH"
+			m := displaySlaveRe.FindStringSubmatch(line)
+			if m == nil {
+				return nil, malformed()
+			}
+			currentAlt.Slaves[m[1]] = m[2]
+			continue
+		}
+
+		var m []string
+		switch {
+		case displayNoBestRe.MatchString(line):
+		case match(displayBestRe, line, &m):
+			result.Best = unquoteDisplay(m[1])
+		case displayAbsentRe.MatchString(line):
+			result.Value = "none"
+		case match(displayValueRe, line, &m):
+			result.Value = m[1]
+		case match(displayLinkRe, line, &m):
+			result.Link = m[1]
+		case match(displaySlaveRe, line, &m):
+			result.Slaves[m[1]] = m[2]
+		default:
+			return nil, &ParseError{
+				Code:    CodeUnexpectedKey,
+				Message: fmt.Sprintf("unexpected line: %s", line),
+				Line:    lineNo,
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	if result.Name == "" {
+		return nil, &ParseError{
+			Code:    CodeUnexpectedEOF,
+			Message: "unexpected end of file",
+			Line:    lineNo,
+		}
+	}
+	if currentAlt != nil {
+		result.Alternatives = append(result.Alternatives, *currentAlt)
+	}
+
+	return result, nil
+}
+
+// Patterns of the lines in the output of --display. See ParseDisplay for the
+// accepted variations.
+var (
+	displayModeRe   = regexp.MustCompile(`(?i)^(.+?) - (?:status is )?(auto|manual)(?: mode)?\.?$`)
+	displayBestRe   = regexp.MustCompile("(?i)^(?:link\\s+)?(?:current\\s+)?[`'\"]?best['\"]?\\s+version\\s+is\\s+(.+)$")
+	displayNoBestRe = regexp.MustCompile(`(?i)^(?:link\s+)?best\s+version\s+(?:is\s+)?not\s+available\.?$`)
+	displayValueRe  = regexp.MustCompile(`(?i)^(?:link\s+)?currently\s+points\s+to\s+(.+)$`)
+	displayAbsentRe = regexp.MustCompile(`(?i)^(?:link\s+)?currently\s+absent\.?$`)
+	displayLinkRe   = regexp.MustCompile(`(?i)^link(?:\s+group)?(?:\s+\S+\s+is|:)\s+(.+)$`)
+	displaySlaveRe  = regexp.MustCompile(`(?i)^slave\s+(\S+?)(?:\s+is|:)\s+(.+)$`)
+)
+
+// match reports whether re matches s, storing the submatches in m.
+func match(re *regexp.Regexp, s string, m *[]string) bool {
+	*m = re.FindStringSubmatch(s)
+	return *m != nil
+}
+
+// unquoteDisplay removes the quotes which older versions of
+// update-alternatives put around a path, as in "'/usr/bin/vim.basic'.".
+func unquoteDisplay(s string) string {
+	if strings.HasSuffix(s, "'.") {
+		s = strings.TrimSuffix(s, ".")
+	}
+	if len(s) >= 2 && (s[0] == '\'' || s[0] == '`') && s[len(s)-1] == '\'' {
+		return s[1 : len(s)-1]
+	}
+	return s
+}
+
+// parseDisplayPriority parses a priority in the output of --display, which
+// may be decorated as in "priority 1081", "(priority 1081)" or "(1081)".
+func parseDisplayPriority(s string) (int, error) {
+	s = strings.TrimSpace(s)
+	if inner, ok := strings.CutPrefix(s, "("); ok {
+		if s, ok = strings.CutSuffix(inner, ")"); !ok {
+			return 0, fmt.Errorf("unbalanced parenthesis: %q", s)
+		}
+	}
+	s = strings.TrimSpace(strings.TrimPrefix(s, "priority"))
+	return parsePriority(s)
+}
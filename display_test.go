@@ -0,0 +1,180 @@
+package queryalternatives_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/kofuk/go-queryalternatives"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_ParseDisplay(t *testing.T) {
+	t.Parallel()
+
+	input := `java - auto mode
+  link best version is /usr/lib/jvm/java-21-openjdk-amd64/bin/java
+  link currently points to /usr/lib/jvm/java-21-openjdk-amd64/bin/java
+  link java is /usr/bin/java
+  slave java.1.gz is /usr/share/man/man1/java.1.gz
+/usr/lib/jvm/java-21-openjdk-amd64/bin/java - priority 2111
+  slave java.1.gz: /usr/lib/jvm/java-21-openjdk-amd64/man/man1/java.1.gz
+/usr/lib/jvm/java-8-openjdk-amd64/jre/bin/java - priority 1081
+  slave java.1.gz: /usr/lib/jvm/java-8-openjdk-amd64/jre/man/man1/java.1.gz
+`
+
+	result, err := queryalternatives.ParseDisplay(strings.NewReader(input))
+	assert.NoError(t, err)
+	assert.Equal(t, newJava(), result)
+}
+
+func Test_ParseDisplay_NoSelection(t *testing.T) {
+	t.Parallel()
+
+	input := `editor - manual mode
+  link best version not available
+  link currently absent
+  link editor is /usr/bin/editor
+`
+
+	result, err := queryalternatives.ParseDisplay(strings.NewReader(input))
+	assert.NoError(t, err)
+	assert.Equal(t, "editor", result.Name)
+	assert.Equal(t, queryalternatives.StatusManual, result.Status)
+	assert.Equal(t, "/usr/bin/editor", result.Link)
+	assert.Equal(t, "", result.Best)
+	assert.Equal(t, "none", result.Value)
+	assert.Empty(t, result.Alternatives)
+}
+
+func Test_ParseDisplay_Priority(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		priority string
+		expected int
+		err      bool
+	}{
+		{name: "with label", priority: "priority 1081", expected: 1081},
+		{name: "with label in parentheses", priority: "(priority 1081)", expected: 1081},
+		{name: "in parentheses", priority: "(1081)", expected: 1081},
+		{name: "bare", priority: "1081", expected: 1081},
+		{name: "negative", priority: "priority -100", expected: -100},
+		{name: "unbalanced", priority: "(1081", err: true},
+		{name: "not a number", priority: "priority high", err: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			input := "java - auto mode\n  link java is /usr/bin/java\n/usr/bin/java-8 - " + test.priority + "\n"
+			result, err := queryalternatives.ParseDisplay(strings.NewReader(input))
+			if test.err {
+				assert.ErrorIs(t, err, queryalternatives.ErrInvalidPriority)
+				return
+			}
+			if assert.NoError(t, err) && assert.Len(t, result.Alternatives, 1) {
+				assert.Equal(t, test.expected, result.Alternatives[0].Priority)
+			}
+		})
+	}
+
+	// The --query format only accepts bare integers.
+	_, err := queryalternatives.ParseString("Name: java\nAlternative: /usr/bin/java-8\nPriority: (1081)\n")
+	assert.ErrorIs(t, err, queryalternatives.ErrInvalidPriority)
+}
+
+func Test_ParseDisplay_Error(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		input    string
+		expected error
+	}{
+		{name: "empty", input: "", expected: queryalternatives.ErrUnexpectedEOF},
+		{name: "malformed first line", input: "java\n", expected: queryalternatives.ErrMalformedLine},
+		{name: "invalid mode", input: "java - sticky mode\n", expected: queryalternatives.ErrInvalidStatus},
+		{name: "unexpected line", input: "java - auto mode\n  frobnicated\n", expected: queryalternatives.ErrUnexpectedKey},
+		{name: "malformed slave", input: "java - auto mode\n/usr/bin/java-8 - priority 1081\n  slave java.1.gz\n", expected: queryalternatives.ErrMalformedLine},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			_, err := queryalternatives.ParseDisplay(strings.NewReader(test.input))
+			assert.ErrorIs(t, err, test.expected)
+		})
+	}
+}
+
+func Test_ParseDisplay_LabelVariants(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name  string
+		input string
+	}{
+		{
+			// Debian 12 (dpkg 1.21)
+			name: "current",
+			input: `java - auto mode
+  link best version is /usr/lib/jvm/java-21-openjdk-amd64/bin/java
+  link currently points to /usr/lib/jvm/java-21-openjdk-amd64/bin/java
+  link java is /usr/bin/java
+  slave java.1.gz is /usr/share/man/man1/java.1.gz
+/usr/lib/jvm/java-21-openjdk-amd64/bin/java - priority 2111
+  slave java.1.gz: /usr/lib/jvm/java-21-openjdk-amd64/man/man1/java.1.gz
+/usr/lib/jvm/java-8-openjdk-amd64/jre/bin/java - priority 1081
+  slave java.1.gz: /usr/lib/jvm/java-8-openjdk-amd64/jre/man/man1/java.1.gz
+`,
+		},
+		{
+			// Ubuntu 12.04 (dpkg 1.16)
+			name: "legacy",
+			input: `java - auto mode
+  link currently points to /usr/lib/jvm/java-21-openjdk-amd64/bin/java
+/usr/lib/jvm/java-21-openjdk-amd64/bin/java - priority 2111
+  slave java.1.gz: /usr/lib/jvm/java-21-openjdk-amd64/man/man1/java.1.gz
+/usr/lib/jvm/java-8-openjdk-amd64/jre/bin/java - priority 1081
+  slave java.1.gz: /usr/lib/jvm/java-8-openjdk-amd64/jre/man/man1/java.1.gz
+Current 'best' version is '/usr/lib/jvm/java-21-openjdk-amd64/bin/java'.
+`,
+		},
+		{
+			name: "link group",
+			input: `java - status is auto.
+  Link group: /usr/bin/java
+  Slave java.1.gz: /usr/share/man/man1/java.1.gz
+  Best version is /usr/lib/jvm/java-21-openjdk-amd64/bin/java
+  Currently points to /usr/lib/jvm/java-21-openjdk-amd64/bin/java
+/usr/lib/jvm/java-21-openjdk-amd64/bin/java - priority 2111
+  slave java.1.gz: /usr/lib/jvm/java-21-openjdk-amd64/man/man1/java.1.gz
+/usr/lib/jvm/java-8-openjdk-amd64/jre/bin/java - priority 1081
+  slave java.1.gz: /usr/lib/jvm/java-8-openjdk-amd64/jre/man/man1/java.1.gz
+`,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			result, err := queryalternatives.ParseDisplay(strings.NewReader(test.input))
+			assert.NoError(t, err)
+			assert.Equal(t, "java", result.Name)
+			assert.Equal(t, queryalternatives.StatusAuto, result.Status)
+			assert.Equal(t, "/usr/lib/jvm/java-21-openjdk-amd64/bin/java", result.Best)
+			assert.Equal(t, "/usr/lib/jvm/java-21-openjdk-amd64/bin/java", result.Value)
+			assert.Len(t, result.Alternatives, 2)
+		})
+	}
+
+	// The link and the slaves are also read from the variant labels.
+	result, err := queryalternatives.ParseDisplay(strings.NewReader(tests[2].input))
+	assert.NoError(t, err)
+	assert.Equal(t, "/usr/bin/java", result.Link)
+	assert.Equal(t, map[string]string{"java.1.gz": "/usr/share/man/man1/java.1.gz"}, result.Slaves)
+}
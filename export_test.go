@@ -0,0 +1,34 @@
+package queryalternatives
+
+// UnregisterTopLevelKey and UnregisterAlternativeKey remove keys registered
+// by tests, so that the tests can run more than once.
+func UnregisterTopLevelKey(key string) {
+	unregister(topLevelKeys, key)
+}
+
+func UnregisterAlternativeKey(key string) {
+	unregister(alternativeKeys, key)
+}
+
+// WithoutOriginalOrder returns a shallow copy of a without the order
+// recorded by the parser or SortByPriority, so that a parsed group can be
+// compared with one built by hand. It returns nil if a is nil.
+func WithoutOriginalOrder(a *Alternatives) *Alternatives {
+	if a == nil {
+		return nil
+	}
+	result := *a
+	result.original = nil
+	return &result
+}
+
+// Waiters returns the number of callers waiting for the query of name.
+func (c *CachingQuerier) Waiters(name string) int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if entry, ok := c.entries[name]; ok {
+		return entry.waiters
+	}
+	return 0
+}
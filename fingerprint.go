@@ -0,0 +1,65 @@
+package queryalternatives
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+)
+
+// Fingerprint returns a hex-encoded SHA-256 digest of the alternatives group.
+// Slaves and alternatives are sorted before hashing, so two groups with the
+// same logical content have the same fingerprint regardless of the order in
+// which they were parsed.
+func (a *Alternatives) Fingerprint() string {
+	h := sha256.New()
+	writeCanonical(h, a)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// writeCanonical writes a canonical serialization of a to w.
+// Each string is written with its length as a prefix so that field
+// boundaries are unambiguous.
+func writeCanonical(w io.Writer, a *Alternatives) {
+	writeString := func(s string) {
+		fmt.Fprintf(w, "%d:%s", len(s), s)
+	}
+	writeSlaves := func(slaves map[string]string) {
+		keys := make([]string, 0, len(slaves))
+		for k := range slaves {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+
+		writeString(strconv.Itoa(len(keys)))
+		for _, k := range keys {
+			writeString(k)
+			writeString(slaves[k])
+		}
+	}
+
+	writeString(a.Name)
+	writeString(a.Link)
+	writeSlaves(a.Slaves)
+	writeString(a.statusText())
+	writeString(a.Best)
+	writeString(a.Value)
+
+	alts := make([]Alternative, len(a.Alternatives))
+	copy(alts, a.Alternatives)
+	sort.SliceStable(alts, func(i, j int) bool {
+		if alts[i].Path != alts[j].Path {
+			return alts[i].Path < alts[j].Path
+		}
+		return alts[i].Priority < alts[j].Priority
+	})
+
+	writeString(strconv.Itoa(len(alts)))
+	for _, alt := range alts {
+		writeString(alt.Path)
+		writeString(strconv.Itoa(alt.Priority))
+		writeSlaves(alt.Slaves)
+	}
+}
@@ -0,0 +1,101 @@
+package queryalternatives_test
+
+import (
+	"testing"
+
+	"github.com/kofuk/go-queryalternatives"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_Fingerprint_OrderIndependent(t *testing.T) {
+	t.Parallel()
+
+	a, err := queryalternatives.ParseString(`Name: java
+Link: /usr/bin/java
+Slaves:
+ java.1.gz /usr/share/man/man1/java.1.gz
+ jexec /usr/bin/jexec
+Status: auto
+Best: /usr/lib/jvm/java-21-openjdk-amd64/bin/java
+Value: /usr/lib/jvm/java-21-openjdk-amd64/bin/java
+
+Alternative: /usr/lib/jvm/java-21-openjdk-amd64/bin/java
+Priority: 2111
+Slaves:
+ java.1.gz /usr/lib/jvm/java-21-openjdk-amd64/man/man1/java.1.gz
+ java.ja.1.gz /usr/lib/jvm/java-21-openjdk-amd64/man/ja/man1/java.1.gz
+
+Alternative: /usr/lib/jvm/java-8-openjdk-amd64/jre/bin/java
+Priority: 1081
+`)
+	require.NoError(t, err)
+
+	b, err := queryalternatives.ParseString(`Name: java
+Link: /usr/bin/java
+Slaves:
+ jexec /usr/bin/jexec
+ java.1.gz /usr/share/man/man1/java.1.gz
+Status: auto
+Best: /usr/lib/jvm/java-21-openjdk-amd64/bin/java
+Value: /usr/lib/jvm/java-21-openjdk-amd64/bin/java
+
+Alternative: /usr/lib/jvm/java-8-openjdk-amd64/jre/bin/java
+Priority: 1081
+
+Alternative: /usr/lib/jvm/java-21-openjdk-amd64/bin/java
+Priority: 2111
+Slaves:
+ java.ja.1.gz /usr/lib/jvm/java-21-openjdk-amd64/man/ja/man1/java.1.gz
+ java.1.gz /usr/lib/jvm/java-21-openjdk-amd64/man/man1/java.1.gz
+`)
+	require.NoError(t, err)
+
+	assert.Len(t, a.Fingerprint(), 64)
+	assert.Equal(t, a.Fingerprint(), b.Fingerprint())
+
+	b.Value = "/usr/lib/jvm/java-8-openjdk-amd64/jre/bin/java"
+	assert.NotEqual(t, a.Fingerprint(), b.Fingerprint())
+}
+
+func Test_Fingerprint_Stable(t *testing.T) {
+	t.Parallel()
+
+	a := &queryalternatives.Alternatives{
+		Name: "editor",
+		Link: "/usr/bin/editor",
+		Slaves: map[string]string{
+			"editor.1.gz": "/usr/share/man/man1/editor.1.gz",
+		},
+		Status: "auto",
+		Best:   "/usr/bin/vim.basic",
+		Value:  "/usr/bin/vim.basic",
+		Alternatives: []queryalternatives.Alternative{
+			{
+				Path:     "/usr/bin/vim.basic",
+				Priority: 30,
+				Slaves: map[string]string{
+					"editor.1.gz": "/usr/share/man/man1/vim.1.gz",
+				},
+			},
+		},
+	}
+
+	// The fingerprint is used as a persistent cache key, so it must not
+	// change between releases.
+	assert.Equal(t, "4d1b135d27215e15e97047ae4a0c3fe6930ab5b96893b4497c834b835d24f110", a.Fingerprint())
+}
+
+func Test_Fingerprint_UnknownStatus(t *testing.T) {
+	t.Parallel()
+
+	sideways := newJava()
+	sideways.Status = queryalternatives.StatusUnknown
+	sideways.StatusRaw = "sideways"
+	bogus := newJava()
+	bogus.Status = queryalternatives.StatusUnknown
+	bogus.StatusRaw = "bogus"
+
+	assert.NotEqual(t, sideways.Fingerprint(), bogus.Fingerprint())
+	assert.NotEqual(t, sideways.Fingerprint(), newJava().Fingerprint())
+}
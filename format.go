@@ -0,0 +1,116 @@
+package queryalternatives
+
+import (
+	"io"
+	"strconv"
+	"strings"
+)
+
+// WriteTo writes a in the format of `update-alternatives --query`, which
+// can be read back with Parser.Parse. Empty fields are omitted, and slaves
+// are written sorted by link name. Unknown is not written, but StatusRaw is
+// written in place of StatusUnknown, and PriorityRaw, if set by
+// Parser.KeepRaw, is written in place of Priority unless it no longer
+// matches Priority, so that the output round-trips exactly.
+func (a *Alternatives) WriteTo(w io.Writer) (int64, error) {
+	var b strings.Builder
+	writeField := func(key, value string) {
+		if value == "" {
+			return
+		}
+		b.WriteString(key)
+		b.WriteString(": ")
+		b.WriteString(value)
+		b.WriteByte('\n')
+	}
+	writeSlaves := func(slaves map[string]string) {
+		if len(slaves) == 0 {
+			return
+		}
+		b.WriteString("Slaves:\n")
+		for _, slave := range slaveLinks(slaves) {
+			b.WriteByte(' ')
+			b.WriteString(slave.Link)
+			b.WriteByte(' ')
+			b.WriteString(slave.Path)
+			b.WriteByte('\n')
+		}
+	}
+
+	writeField("Name", a.Name)
+	writeField("Link", a.Link)
+	writeSlaves(a.Slaves)
+	writeField("Status", a.statusText())
+	writeField("Best", a.Best)
+	writeField("Value", a.Value)
+
+	for _, alt := range a.Alternatives {
+		b.WriteByte('\n')
+		writeField("Alternative", alt.Path)
+		priority := strconv.Itoa(alt.Priority)
+		if p, err := parsePriority(alt.PriorityRaw); err == nil && p == alt.Priority {
+			priority = alt.PriorityRaw
+		}
+		writeField("Priority", priority)
+		writeSlaves(alt.Slaves)
+	}
+
+	n, err := io.WriteString(w, b.String())
+	return int64(n), err
+}
+
+// statusText returns the text of the status: StatusRaw for StatusUnknown if
+// it is set, and Status otherwise.
+func (a *Alternatives) statusText() string {
+	if a.Status == StatusUnknown && a.StatusRaw != "" {
+		return a.StatusRaw
+	}
+	return string(a.Status)
+}
+
+// DOT returns a Graphviz digraph of the symlinks of the group: the generic
+// link and each slave link point at their entries in /etc/alternatives,
+// which in turn point at the selected alternative and its slaves.
+// Entries in /etc/alternatives are omitted for slaves which the selected
+// alternative does not provide, and without a selection only the links to
+// /etc/alternatives are drawn.
+func (a *Alternatives) DOT() string {
+	var b strings.Builder
+	edge := func(from, to string) {
+		b.WriteString("\t")
+		b.WriteString(dotQuote(from))
+		b.WriteString(" -> ")
+		b.WriteString(dotQuote(to))
+		b.WriteString(";\n")
+	}
+
+	selected := a.selected()
+
+	b.WriteString("digraph ")
+	b.WriteString(dotQuote(a.Name))
+	b.WriteString(" {\n")
+
+	entry := "/etc/alternatives/" + a.Name
+	edge(a.Link, entry)
+	if selected != nil {
+		edge(entry, selected.Path)
+	}
+	for _, slave := range a.GroupSlaveTargets() {
+		entry := "/etc/alternatives/" + slave.Link
+		edge(slave.Path, entry)
+		if selected == nil {
+			continue
+		}
+		if target, ok := selected.Slaves[slave.Link]; ok {
+			edge(entry, target)
+		}
+	}
+
+	b.WriteString("}\n")
+	return b.String()
+}
+
+// dotQuote returns s as a quoted Graphviz ID.
+func dotQuote(s string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(s) + `"`
+}
@@ -0,0 +1,63 @@
+package queryalternatives_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/kofuk/go-queryalternatives"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_Alternatives_WriteTo(t *testing.T) {
+	t.Parallel()
+
+	var b strings.Builder
+	n, err := newJava().WriteTo(&b)
+	assert.NoError(t, err)
+	assert.Equal(t, `Name: java
+Link: /usr/bin/java
+Slaves:
+ java.1.gz /usr/share/man/man1/java.1.gz
+Status: auto
+Best: /usr/lib/jvm/java-21-openjdk-amd64/bin/java
+Value: /usr/lib/jvm/java-21-openjdk-amd64/bin/java
+
+Alternative: /usr/lib/jvm/java-21-openjdk-amd64/bin/java
+Priority: 2111
+Slaves:
+ java.1.gz /usr/lib/jvm/java-21-openjdk-amd64/man/man1/java.1.gz
+
+Alternative: /usr/lib/jvm/java-8-openjdk-amd64/jre/bin/java
+Priority: 1081
+Slaves:
+ java.1.gz /usr/lib/jvm/java-8-openjdk-amd64/jre/man/man1/java.1.gz
+`, b.String())
+	assert.Equal(t, int64(b.Len()), n)
+
+	result, err := queryalternatives.ParseString(b.String())
+	assert.NoError(t, err)
+	assertEqualGroup(t, newJava(), result)
+}
+
+func Test_Alternatives_DOT(t *testing.T) {
+	t.Parallel()
+
+	a := newJava()
+	a.Slaves["jexec"] = "/usr/bin/jexec"
+	assert.Equal(t, `digraph "java" {
+	"/usr/bin/java" -> "/etc/alternatives/java";
+	"/etc/alternatives/java" -> "/usr/lib/jvm/java-21-openjdk-amd64/bin/java";
+	"/usr/share/man/man1/java.1.gz" -> "/etc/alternatives/java.1.gz";
+	"/etc/alternatives/java.1.gz" -> "/usr/lib/jvm/java-21-openjdk-amd64/man/man1/java.1.gz";
+	"/usr/bin/jexec" -> "/etc/alternatives/jexec";
+}
+`, a.DOT())
+
+	a.Value = "none"
+	dot := a.DOT()
+	assert.Contains(t, dot, `"/usr/bin/java" -> "/etc/alternatives/java";`)
+	assert.NotContains(t, dot, `"/etc/alternatives/java" ->`)
+
+	a.Name = `ja"va`
+	assert.Contains(t, a.DOT(), `digraph "ja\"va" {`)
+}
@@ -0,0 +1,39 @@
+package queryalternatives
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io"
+)
+
+// ParseGzip parses the gzip-compressed output of
+// `update-alternatives --query`, such as an archived query.
+// It fails if r is not valid gzip, including when the compressed data is
+// corrupted or truncated.
+func ParseGzip(r io.Reader) (*Alternatives, error) {
+	zr, err := newGzipReader(r)
+	if err != nil {
+		return nil, err
+	}
+	defer zr.Close()
+	return NewParser(zr).Parse()
+}
+
+// ParseAllGzip is like ParseGzip, but parses all groups in the input.
+// See Parser.ParseAll for the input format.
+func ParseAllGzip(r io.Reader) ([]*Alternatives, error) {
+	zr, err := newGzipReader(r)
+	if err != nil {
+		return nil, err
+	}
+	defer zr.Close()
+	return NewParser(zr).ParseAll()
+}
+
+func newGzipReader(r io.Reader) (*gzip.Reader, error) {
+	zr, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, fmt.Errorf("invalid gzip input: %w", err)
+	}
+	return zr, nil
+}
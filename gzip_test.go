@@ -0,0 +1,55 @@
+package queryalternatives_test
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/kofuk/go-queryalternatives"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func gzipString(t *testing.T, s string) []byte {
+	t.Helper()
+
+	var b bytes.Buffer
+	w := gzip.NewWriter(&b)
+	_, err := io.WriteString(w, s)
+	require.NoError(t, err)
+	require.NoError(t, w.Close())
+	return b.Bytes()
+}
+
+func Test_ParseGzip(t *testing.T) {
+	t.Parallel()
+
+	result, err := queryalternatives.ParseGzip(bytes.NewReader(gzipString(t, javaQuery)))
+	require.NoError(t, err)
+	expected, err := queryalternatives.ParseString(javaQuery)
+	require.NoError(t, err)
+	assert.Equal(t, expected, result)
+
+	_, err = queryalternatives.ParseGzip(strings.NewReader(javaQuery))
+	assert.ErrorIs(t, err, gzip.ErrHeader)
+	assert.ErrorContains(t, err, "invalid gzip input")
+
+	compressed := gzipString(t, javaQuery)
+	_, err = queryalternatives.ParseGzip(bytes.NewReader(compressed[:len(compressed)-4]))
+	assert.ErrorIs(t, err, io.ErrUnexpectedEOF)
+}
+
+func Test_ParseAllGzip(t *testing.T) {
+	t.Parallel()
+
+	result, err := queryalternatives.ParseAllGzip(bytes.NewReader(gzipString(t, javaQuery+"\n"+editorQuery)))
+	require.NoError(t, err)
+	require.Len(t, result, 2)
+	assert.Equal(t, "java", result[0].Name)
+	assert.Equal(t, "editor", result[1].Name)
+
+	_, err = queryalternatives.ParseAllGzip(strings.NewReader(""))
+	assert.ErrorContains(t, err, "invalid gzip input")
+}
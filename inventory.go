@@ -0,0 +1,248 @@
+package queryalternatives
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// Index returns a map from group name to group.
+// If several groups have the same name, the last one wins.
+func Index(groups []*Alternatives) map[string]*Alternatives {
+	result := make(map[string]*Alternatives, len(groups))
+	for _, g := range groups {
+		result[g.Name] = g
+	}
+	return result
+}
+
+// filterByStatus returns the groups whose status is status, keeping their order.
+func filterByStatus(groups []*Alternatives, status Status) []*Alternatives {
+	result := make([]*Alternatives, 0)
+	for _, g := range groups {
+		if g.Status == status {
+			result = append(result, g)
+		}
+	}
+	return result
+}
+
+// ManualGroups returns the groups in manual mode, keeping their order.
+func ManualGroups(groups []*Alternatives) []*Alternatives {
+	return filterByStatus(groups, StatusManual)
+}
+
+// AutoGroups returns the groups in auto mode, keeping their order.
+func AutoGroups(groups []*Alternatives) []*Alternatives {
+	return filterByStatus(groups, StatusAuto)
+}
+
+// FindSlaveConflicts returns a map from slave name to the names of the groups
+// which declare it, for each slave declared by more than one group.
+// Slave names share the namespace of /etc/alternatives, so such a slave is a
+// misconfiguration. The group names are sorted.
+func FindSlaveConflicts(groups []*Alternatives) map[string][]string {
+	owners := make(map[string][]string)
+	for _, g := range groups {
+		for name := range g.Slaves {
+			if !slices.Contains(owners[name], g.Name) {
+				owners[name] = append(owners[name], g.Name)
+			}
+		}
+	}
+
+	result := make(map[string][]string)
+	for name, groupNames := range owners {
+		if len(groupNames) > 1 {
+			slices.Sort(groupNames)
+			result[name] = groupNames
+		}
+	}
+	return result
+}
+
+// WriteSelections writes groups to w in the format of
+// `update-alternatives --get-selections`, one line per group with its name,
+// status and value, in the given order. The result can be replayed with
+// `update-alternatives --set-selections`.
+func WriteSelections(w io.Writer, groups []*Alternatives) error {
+	for _, g := range groups {
+		value := g.Value
+		if value == "" {
+			value = "none"
+		}
+		if _, err := fmt.Fprintf(w, "%-30s %-8s %s\n", g.Name, g.Status, value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// WriteManualSelections is like WriteSelections, but only writes the groups
+// in manual mode, which are the ones overridden by an operator. Groups in
+// auto mode follow the priorities and need not be backed up.
+func WriteManualSelections(w io.Writer, groups []*Alternatives) error {
+	return WriteSelections(w, ManualGroups(groups))
+}
+
+// WriteInventory writes groups to w in the format of
+// `update-alternatives --query` (see Alternatives.WriteTo), separated by
+// empty lines. The result can be read back with ReadInventory.
+func WriteInventory(w io.Writer, groups []*Alternatives) error {
+	for i, g := range groups {
+		if i > 0 {
+			if _, err := io.WriteString(w, "\n"); err != nil {
+				return err
+			}
+		}
+		if _, err := g.WriteTo(w); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ReadInventory reads groups written by WriteInventory.
+func ReadInventory(r io.Reader) ([]*Alternatives, error) {
+	return NewParser(r).ParseAll()
+}
+
+// BuildIndex scans the size bytes of r, which contains groups such as those
+// written by WriteInventory, and returns a map from the name of each group to
+// the byte offset of its `Name:` line. A single group can then be parsed
+// without parsing the rest, for example with
+// NewParser(io.NewSectionReader(r, offset, size-offset)).Decode().
+// If several groups have the same name, the last one wins.
+func BuildIndex(r io.ReaderAt, size int64) (map[string]int64, error) {
+	br := bufio.NewReader(io.NewSectionReader(r, 0, size))
+	result := make(map[string]int64)
+	var offset int64
+	for {
+		line, err := br.ReadBytes('\n')
+		if name, ok := bytes.CutPrefix(line, []byte("Name:")); ok {
+			result[string(bytes.TrimSpace(name))] = offset
+		}
+		offset += int64(len(line))
+		if err != nil {
+			if err == io.EOF {
+				return result, nil
+			}
+			return nil, err
+		}
+	}
+}
+
+// FilterStream reads groups from r one by one (see Parser.Decode) and writes
+// those for which keep returns true to w in the same format as
+// WriteInventory. Only a single group is held in memory at a time.
+// It stops at the first error.
+func FilterStream(r io.Reader, w io.Writer, keep func(*Alternatives) bool) error {
+	parser := NewParser(r)
+	written := false
+	for {
+		g, err := parser.Decode()
+		if err != nil {
+			if err == io.EOF {
+				return nil
+			}
+			return err
+		}
+		if !keep(g) {
+			continue
+		}
+
+		if written {
+			if _, err := io.WriteString(w, "\n"); err != nil {
+				return err
+			}
+		}
+		if _, err := g.WriteTo(w); err != nil {
+			return err
+		}
+		written = true
+	}
+}
+
+// FindFirst reads groups from r one by one (see Parser.Decode) and returns
+// the first one for which pred returns true, without reading the rest of the
+// input. If no group matches, an error matched by errors.Is with ErrNotFound
+// is returned. It stops at the first error.
+func FindFirst(r io.Reader, pred func(*Alternatives) bool) (*Alternatives, error) {
+	parser := NewParser(r)
+	for {
+		g, err := parser.Decode()
+		if err != nil {
+			if err == io.EOF {
+				return nil, fmt.Errorf("no matching group: %w", ErrNotFound)
+			}
+			return nil, err
+		}
+		if pred(g) {
+			return g, nil
+		}
+	}
+}
+
+// AllPaths returns the sorted paths of all alternatives in groups, without
+// duplicates.
+func AllPaths(groups []*Alternatives) []string {
+	result := make([]string, 0)
+	for _, g := range groups {
+		for _, alt := range g.Alternatives {
+			result = append(result, alt.Path)
+		}
+	}
+	slices.Sort(result)
+	return slices.Compact(result)
+}
+
+// GroupBySelectedPath returns a map from the selected path to the names of
+// the groups which select it, keeping their order. Groups without a
+// selection are skipped.
+func GroupBySelectedPath(groups []*Alternatives) map[string][]string {
+	result := make(map[string][]string)
+	for _, g := range groups {
+		if isNone(g.Value) {
+			continue
+		}
+		result[g.Value] = append(result[g.Value], g.Name)
+	}
+	return result
+}
+
+// AutoDriftGroups returns the groups which drift from their best
+// alternative in auto mode (see Alternatives.DriftsFromBest), sorted by
+// name.
+func AutoDriftGroups(groups []*Alternatives) []*Alternatives {
+	result := make([]*Alternatives, 0)
+	for _, g := range groups {
+		if g.DriftsFromBest() {
+			result = append(result, g)
+		}
+	}
+	slices.SortStableFunc(result, func(a, b *Alternatives) int {
+		return strings.Compare(a.Name, b.Name)
+	})
+	return result
+}
+
+// GroupByDir returns the alternatives of the group keyed by the directory
+// of their paths, such as "/usr/lib/jvm/java-21-openjdk-amd64/bin".
+// The alternatives in each directory are sorted by path.
+func (a *Alternatives) GroupByDir() map[string][]Alternative {
+	result := make(map[string][]Alternative)
+	for _, alt := range a.Alternatives {
+		dir := filepath.Dir(alt.Path)
+		result[dir] = append(result[dir], alt)
+	}
+	for _, alts := range result {
+		slices.SortStableFunc(alts, func(x, y Alternative) int {
+			return strings.Compare(x.Path, y.Path)
+		})
+	}
+	return result
+}
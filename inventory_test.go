@@ -0,0 +1,344 @@
+package queryalternatives_test
+
+import (
+	"errors"
+	"io"
+	"maps"
+	"slices"
+	"strings"
+	"testing"
+	"testing/iotest"
+
+	"github.com/kofuk/go-queryalternatives"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_Index(t *testing.T) {
+	t.Parallel()
+
+	java := newJava()
+	editor := newEditor()
+
+	index := queryalternatives.Index([]*queryalternatives.Alternatives{java, editor})
+	assert.Len(t, index, 2)
+	assert.Same(t, java, index["java"])
+	assert.Same(t, editor, index["editor"])
+}
+
+func Test_ManualGroups_AutoGroups(t *testing.T) {
+	t.Parallel()
+
+	java := newJava()
+	editor := newEditor()
+	pager := &queryalternatives.Alternatives{Name: "pager", Status: queryalternatives.StatusManual}
+	groups := []*queryalternatives.Alternatives{java, editor, pager}
+
+	assert.Equal(t, []*queryalternatives.Alternatives{editor, pager}, queryalternatives.ManualGroups(groups))
+	assert.Equal(t, []*queryalternatives.Alternatives{java}, queryalternatives.AutoGroups(groups))
+
+	assert.Empty(t, queryalternatives.ManualGroups(nil))
+	assert.Empty(t, queryalternatives.AutoGroups(nil))
+}
+
+func Test_FindSlaveConflicts(t *testing.T) {
+	t.Parallel()
+
+	java := newJava()
+	java.Slaves["jexec"] = "/usr/bin/jexec"
+	javac := &queryalternatives.Alternatives{
+		Name: "javac",
+		Slaves: map[string]string{
+			"java.1.gz":  "/usr/share/man/man1/java.1.gz",
+			"javac.1.gz": "/usr/share/man/man1/javac.1.gz",
+		},
+	}
+	jexec := &queryalternatives.Alternatives{
+		Name: "jexec",
+		Slaves: map[string]string{
+			"jexec":     "/usr/bin/jexec",
+			"java.1.gz": "/usr/share/man/man1/java.1.gz",
+		},
+	}
+
+	result := queryalternatives.FindSlaveConflicts([]*queryalternatives.Alternatives{jexec, java, newEditor(), javac})
+	assert.Equal(t, map[string][]string{
+		"java.1.gz": {"java", "javac", "jexec"},
+		"jexec":     {"java", "jexec"},
+	}, result)
+
+	assert.Empty(t, queryalternatives.FindSlaveConflicts([]*queryalternatives.Alternatives{newJava(), newEditor()}))
+}
+
+func Test_WriteInventory_ReadInventory(t *testing.T) {
+	t.Parallel()
+
+	none := &queryalternatives.Alternatives{
+		Name:         "pager",
+		Link:         "/usr/bin/pager",
+		Slaves:       map[string]string{},
+		Status:       queryalternatives.StatusAuto,
+		Value:        "none",
+		Alternatives: []queryalternatives.Alternative{},
+	}
+	groups := []*queryalternatives.Alternatives{newJava(), newEditor(), none}
+
+	var b strings.Builder
+	assert.NoError(t, queryalternatives.WriteInventory(&b, groups))
+
+	result, err := queryalternatives.ReadInventory(strings.NewReader(b.String()))
+	assert.NoError(t, err)
+	if assert.Len(t, result, len(groups)) {
+		for i := range groups {
+			assertEqualGroup(t, groups[i], result[i])
+		}
+	}
+
+	var again strings.Builder
+	assert.NoError(t, queryalternatives.WriteInventory(&again, result))
+	assert.Equal(t, b.String(), again.String())
+
+	// With KeepRaw, priorities are written as they were read.
+	raw := strings.Replace(b.String(), "Priority: 40\n", "Priority: 040\n", 1)
+	parser := queryalternatives.NewParser(strings.NewReader(raw))
+	parser.KeepRaw = true
+	result, err = parser.ParseAll()
+	require.NoError(t, err)
+	again.Reset()
+	assert.NoError(t, queryalternatives.WriteInventory(&again, result))
+	assert.Equal(t, raw, again.String())
+
+	// A stale PriorityRaw is ignored.
+	result[1].Alternatives[0].Priority = 41
+	again.Reset()
+	assert.NoError(t, queryalternatives.WriteInventory(&again, result))
+	assert.Contains(t, again.String(), "Priority: 41\n")
+}
+
+func Test_WriteSelections(t *testing.T) {
+	t.Parallel()
+
+	none := &queryalternatives.Alternatives{
+		Name:   "pager",
+		Status: queryalternatives.StatusAuto,
+	}
+	groups := []*queryalternatives.Alternatives{newJava(), newEditor(), none}
+
+	var b strings.Builder
+	assert.NoError(t, queryalternatives.WriteSelections(&b, groups))
+	assert.Equal(t, ""+
+		"java                           auto     /usr/lib/jvm/java-21-openjdk-amd64/bin/java\n"+
+		"editor                         manual   /bin/nano\n"+
+		"pager                          auto     none\n", b.String())
+}
+
+func Test_WriteManualSelections(t *testing.T) {
+	t.Parallel()
+
+	vi := newEditor()
+	vi.Name = "vi"
+	vi.Value = "/usr/bin/vim.basic"
+	groups := []*queryalternatives.Alternatives{newJava(), newEditor(), vi}
+
+	var b strings.Builder
+	assert.NoError(t, queryalternatives.WriteManualSelections(&b, groups))
+	assert.Equal(t, ""+
+		"editor                         manual   /bin/nano\n"+
+		"vi                             manual   /usr/bin/vim.basic\n", b.String())
+
+	b.Reset()
+	assert.NoError(t, queryalternatives.WriteManualSelections(&b, []*queryalternatives.Alternatives{newJava()}))
+	assert.Empty(t, b.String())
+}
+
+func Test_AllPaths(t *testing.T) {
+	t.Parallel()
+
+	vi := &queryalternatives.Alternatives{
+		Name: "vi",
+		Alternatives: []queryalternatives.Alternative{
+			{Path: "/usr/bin/vim.basic", Priority: 30},
+			{Path: "/usr/bin/vim.tiny", Priority: 15},
+		},
+	}
+	empty := &queryalternatives.Alternatives{Name: "pager"}
+
+	assert.Equal(t, []string{
+		"/bin/nano",
+		"/usr/bin/vim.basic",
+		"/usr/bin/vim.tiny",
+		"/usr/lib/jvm/java-21-openjdk-amd64/bin/java",
+		"/usr/lib/jvm/java-8-openjdk-amd64/jre/bin/java",
+	}, queryalternatives.AllPaths([]*queryalternatives.Alternatives{newJava(), newEditor(), vi, empty}))
+
+	assert.Empty(t, queryalternatives.AllPaths(nil))
+	assert.Empty(t, queryalternatives.AllPaths([]*queryalternatives.Alternatives{empty}))
+}
+
+func Test_GroupBySelectedPath(t *testing.T) {
+	t.Parallel()
+
+	jre := &queryalternatives.Alternatives{Name: "jre", Value: "/usr/lib/jvm/java-21-openjdk-amd64/bin/java"}
+	jshell := &queryalternatives.Alternatives{Name: "jshell", Value: "/usr/lib/jvm/java-21-openjdk-amd64/bin/java"}
+	none := &queryalternatives.Alternatives{Name: "pager", Value: "none"}
+
+	assert.Equal(t, map[string][]string{
+		"/usr/lib/jvm/java-21-openjdk-amd64/bin/java": {"java", "jre", "jshell"},
+		"/bin/nano": {"editor"},
+	}, queryalternatives.GroupBySelectedPath([]*queryalternatives.Alternatives{newJava(), jre, newEditor(), none, jshell}))
+
+	assert.Empty(t, queryalternatives.GroupBySelectedPath([]*queryalternatives.Alternatives{none}))
+}
+
+func Test_AutoDriftGroups(t *testing.T) {
+	t.Parallel()
+
+	java := newJava()
+	java.Value = "/usr/lib/jvm/java-8-openjdk-amd64/jre/bin/java"
+	awk := &queryalternatives.Alternatives{
+		Name:   "awk",
+		Status: queryalternatives.StatusAuto,
+		Best:   "/usr/bin/gawk",
+		Value:  "/usr/bin/mawk",
+	}
+	healthy := newJava()
+	healthy.Name = "jre"
+
+	groups := []*queryalternatives.Alternatives{java, healthy, newEditor(), awk}
+	assert.Equal(t, []*queryalternatives.Alternatives{awk, java}, queryalternatives.AutoDriftGroups(groups))
+
+	assert.Empty(t, queryalternatives.AutoDriftGroups([]*queryalternatives.Alternatives{healthy, newEditor()}))
+}
+
+func Test_GroupByDir(t *testing.T) {
+	t.Parallel()
+
+	a := &queryalternatives.Alternatives{
+		Name: "editor",
+		Alternatives: []queryalternatives.Alternative{
+			{Path: "/usr/bin/vim.tiny", Priority: 15},
+			{Path: "/bin/nano", Priority: 40},
+			{Path: "/usr/bin/vim.basic", Priority: 30},
+			{Path: "/bin/ed", Priority: -100},
+			{Path: "/usr/local/bin/nvim", Priority: 50},
+		},
+	}
+
+	assert.Equal(t, map[string][]queryalternatives.Alternative{
+		"/bin": {
+			{Path: "/bin/ed", Priority: -100},
+			{Path: "/bin/nano", Priority: 40},
+		},
+		"/usr/bin": {
+			{Path: "/usr/bin/vim.basic", Priority: 30},
+			{Path: "/usr/bin/vim.tiny", Priority: 15},
+		},
+		"/usr/local/bin": {
+			{Path: "/usr/local/bin/nvim", Priority: 50},
+		},
+	}, a.GroupByDir())
+
+	assert.Empty(t, (&queryalternatives.Alternatives{}).GroupByDir())
+}
+
+func Test_FilterStream(t *testing.T) {
+	t.Parallel()
+
+	pager := &queryalternatives.Alternatives{
+		Name:         "pager",
+		Link:         "/usr/bin/pager",
+		Slaves:       map[string]string{},
+		Status:       queryalternatives.StatusManual,
+		Value:        "/bin/less",
+		Alternatives: []queryalternatives.Alternative{{Path: "/bin/less", Priority: 77, Slaves: map[string]string{}}},
+	}
+	var input strings.Builder
+	require.NoError(t, queryalternatives.WriteInventory(&input, []*queryalternatives.Alternatives{newJava(), newEditor(), pager}))
+
+	var output strings.Builder
+	err := queryalternatives.FilterStream(strings.NewReader(input.String()), &output, func(g *queryalternatives.Alternatives) bool {
+		return g.Status == queryalternatives.StatusManual
+	})
+	require.NoError(t, err)
+
+	var expected strings.Builder
+	require.NoError(t, queryalternatives.WriteInventory(&expected, []*queryalternatives.Alternatives{newEditor(), pager}))
+	assert.Equal(t, expected.String(), output.String())
+
+	output.Reset()
+	err = queryalternatives.FilterStream(strings.NewReader("Name: java\nStatus: sideways\n"), &output, func(*queryalternatives.Alternatives) bool {
+		return true
+	})
+	assert.ErrorIs(t, err, queryalternatives.ErrInvalidStatus)
+	assert.Empty(t, output.String())
+}
+
+func Test_FindFirst(t *testing.T) {
+	t.Parallel()
+
+	var input strings.Builder
+	require.NoError(t, queryalternatives.WriteInventory(&input, []*queryalternatives.Alternatives{newJava(), newEditor()}))
+	input.WriteString("\nName: pager\nLink: /usr/bin/pager\n")
+
+	// Reading beyond the beginning of the group following the match fails,
+	// so the test fails unless FindFirst stops there.
+	unread := errors.New("read beyond the match")
+	r := io.MultiReader(strings.NewReader(input.String()), iotest.ErrReader(unread))
+
+	result, err := queryalternatives.FindFirst(r, func(g *queryalternatives.Alternatives) bool {
+		return g.Status == queryalternatives.StatusManual
+	})
+	require.NoError(t, err)
+	assertEqualGroup(t, newEditor(), result)
+
+	_, err = queryalternatives.FindFirst(strings.NewReader(input.String()), func(g *queryalternatives.Alternatives) bool {
+		return g.Name == "vi"
+	})
+	assert.ErrorIs(t, err, queryalternatives.ErrNotFound)
+
+	_, err = queryalternatives.FindFirst(r, func(*queryalternatives.Alternatives) bool {
+		return false
+	})
+	assert.ErrorIs(t, err, unread)
+}
+
+func Test_BuildIndex(t *testing.T) {
+	t.Parallel()
+
+	pager := &queryalternatives.Alternatives{
+		Name:         "pager",
+		Link:         "/usr/bin/pager",
+		Slaves:       map[string]string{},
+		Status:       queryalternatives.StatusManual,
+		Value:        "/bin/less",
+		Alternatives: []queryalternatives.Alternative{{Path: "/bin/less", Priority: 77, Slaves: map[string]string{}}},
+	}
+	groups := []*queryalternatives.Alternatives{newJava(), newEditor(), pager}
+	var b strings.Builder
+	require.NoError(t, queryalternatives.WriteInventory(&b, groups))
+	input := b.String()
+	r := strings.NewReader(input)
+
+	index, err := queryalternatives.BuildIndex(r, int64(len(input)))
+	require.NoError(t, err)
+	assert.Len(t, index, 3)
+
+	for _, g := range groups {
+		offset, ok := index[g.Name]
+		require.True(t, ok, g.Name)
+		assert.True(t, strings.HasPrefix(input[offset:], "Name: "+g.Name+"\n"), g.Name)
+
+		result, err := queryalternatives.NewParser(io.NewSectionReader(r, offset, int64(len(input))-offset)).Decode()
+		require.NoError(t, err)
+		assertEqualGroup(t, g, result)
+	}
+
+	// Only size bytes are scanned.
+	index, err = queryalternatives.BuildIndex(r, int64(strings.Index(input, "Name: pager")))
+	require.NoError(t, err)
+	assert.Equal(t, []string{"editor", "java"}, slices.Sorted(maps.Keys(index)))
+
+	index, err = queryalternatives.BuildIndex(r, 0)
+	assert.NoError(t, err)
+	assert.Empty(t, index)
+}
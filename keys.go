@@ -0,0 +1,179 @@
+package queryalternatives
+
+import (
+	"fmt"
+	"sync"
+)
+
+// keyFunc interprets the value of a key for the group being built by b.
+type keyFunc func(b *builder, v string) error
+
+var (
+	keysMu sync.RWMutex
+	// topLevelKeys and alternativeKeys hold the interpreters of the keys
+	// at the group level and in an alternative, respectively.
+	topLevelKeys    = map[string]keyFunc{}
+	alternativeKeys = map[string]keyFunc{}
+)
+
+func init() {
+	topLevelKeys["Name"] = func(b *builder, v string) error {
+		b.result.Name = v
+		return nil
+	}
+	topLevelKeys["Link"] = func(b *builder, v string) error {
+		b.result.Link = v
+		return nil
+	}
+	topLevelKeys["Slaves"] = func(b *builder, v string) error {
+		slaves, err := b.r.parseSlaves(v)
+		if err != nil {
+			return err
+		}
+		b.result.Slaves = slaves
+		return nil
+	}
+	topLevelKeys["Status"] = func(b *builder, v string) error {
+		status, err := b.r.parseStatus(v)
+		if err != nil {
+			if !b.r.Lenient {
+				return err
+			}
+			b.result.Status = StatusUnknown
+			b.result.StatusRaw = v
+			return nil
+		}
+		b.result.Status = status
+		return nil
+	}
+	topLevelKeys["Best"] = func(b *builder, v string) error {
+		b.result.Best = v
+		return nil
+	}
+	topLevelKeys["Value"] = func(b *builder, v string) error {
+		b.result.Value = v
+		return nil
+	}
+	topLevelKeys["Alternative"] = startAlternative
+
+	alternativeKeys["Priority"] = func(b *builder, v string) error {
+		priority, err := parsePriority(v)
+		if err != nil {
+			return &ParseError{
+				Code:    CodeInvalidPriority,
+				Message: "invalid priority value",
+				Line:    b.r.lineNo,
+			}
+		}
+		if priority < 0 && b.r.RejectNegativePriority {
+			return &ParseError{
+				Code:    CodeNegativePriority,
+				Message: "negative priority value",
+				Line:    b.r.lineNo,
+			}
+		}
+		b.currentAlt.Priority = priority
+		if b.r.KeepRaw {
+			b.currentAlt.PriorityRaw = v
+		}
+		return nil
+	}
+	alternativeKeys["Slaves"] = func(b *builder, v string) error {
+		slaves, err := b.r.parseSlaves(v)
+		if err != nil {
+			return err
+		}
+		b.currentAlt.Slaves = slaves
+		return nil
+	}
+	alternativeKeys["Alternative"] = startAlternative
+}
+
+// startAlternative starts a new alternative whose path is v, saving the
+// previous one, if any.
+func startAlternative(b *builder, v string) error {
+	if b.currentAlt != nil {
+		b.result.Alternatives = append(b.result.Alternatives, *b.currentAlt)
+	}
+	b.currentAlt = newAlternative()
+	b.currentAlt.Path = v
+	return nil
+}
+
+// RegisterTopLevelKey teaches the parser a new group-level key, which may
+// appear before the first `Alternative:` line. set is called with the group
+// being parsed and the value of the key. If it returns an error, parsing
+// fails with a ParseError whose message includes the key and the error.
+// Registered keys are never stored in Unknown.
+//
+// RegisterTopLevelKey is meant to be called from an init function. It
+// panics if set is nil or key is empty or already registered, including
+// the built-in keys such as "Name".
+func RegisterTopLevelKey(key string, set func(*Alternatives, string) error) {
+	if set == nil {
+		panic("queryalternatives: RegisterTopLevelKey: nil set function")
+	}
+	register(topLevelKeys, "RegisterTopLevelKey", key, func(b *builder, v string) error {
+		return b.r.keyError(key, set(b.result, v))
+	})
+}
+
+// RegisterAlternativeKey is like RegisterTopLevelKey, but teaches the parser
+// a new key in an alternative, which may appear after an `Alternative:`
+// line. set is called with the alternative being parsed.
+func RegisterAlternativeKey(key string, set func(*Alternative, string) error) {
+	if set == nil {
+		panic("queryalternatives: RegisterAlternativeKey: nil set function")
+	}
+	register(alternativeKeys, "RegisterAlternativeKey", key, func(b *builder, v string) error {
+		return b.r.keyError(key, set(b.currentAlt, v))
+	})
+}
+
+func register(keys map[string]keyFunc, caller, key string, f keyFunc) {
+	keysMu.Lock()
+	defer keysMu.Unlock()
+
+	if key == "" {
+		panic(fmt.Sprintf("queryalternatives: %s: empty key", caller))
+	}
+	if _, ok := keys[key]; ok {
+		panic(fmt.Sprintf("queryalternatives: %s: key %q is already registered", caller, key))
+	}
+	keys[key] = f
+}
+
+// unregister removes key from keys. It is only used by tests.
+func unregister(keys map[string]keyFunc, key string) {
+	keysMu.Lock()
+	defer keysMu.Unlock()
+
+	delete(keys, key)
+}
+
+// lookupKey returns the interpreter of key at the group level, or in an
+// alternative if alt is true.
+func lookupKey(key string, alt bool) (keyFunc, bool) {
+	keysMu.RLock()
+	defer keysMu.RUnlock()
+
+	if alt {
+		f, ok := alternativeKeys[key]
+		return f, ok
+	}
+	f, ok := topLevelKeys[key]
+	return f, ok
+}
+
+// keyError converts err returned by a registered function for key into a
+// ParseError at the current line. It returns nil if err is nil.
+func (r *Parser) keyError(key string, err error) error {
+	if err == nil {
+		return nil
+	}
+	return &ParseError{
+		Code:    CodeUnknown,
+		Message: fmt.Sprintf("%s: %v", key, err),
+		Line:    r.lineNo,
+	}
+}
@@ -0,0 +1,112 @@
+package queryalternatives_test
+
+import (
+	"errors"
+	"strconv"
+	"testing"
+
+	"github.com/kofuk/go-queryalternatives"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_RegisterTopLevelKey(t *testing.T) {
+	t.Parallel()
+
+	var vendors []string
+	queryalternatives.RegisterTopLevelKey("X-Test-Vendor", func(a *queryalternatives.Alternatives, v string) error {
+		if v == "" {
+			return errors.New("empty vendor")
+		}
+		vendors = append(vendors, a.Name+"="+v)
+		return nil
+	})
+	t.Cleanup(func() { queryalternatives.UnregisterTopLevelKey("X-Test-Vendor") })
+
+	result, err := queryalternatives.ParseString(`Name: java
+Link: /usr/bin/java
+X-Test-Vendor: openjdk
+Status: auto
+Best: /usr/bin/java-21
+Value: /usr/bin/java-21
+
+Alternative: /usr/bin/java-21
+Priority: 2111
+`)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"java=openjdk"}, vendors)
+	assert.Equal(t, queryalternatives.StatusAuto, result.Status)
+	assert.Empty(t, result.Unknown)
+
+	// The key is not recognized in an alternative.
+	_, err = queryalternatives.ParseString(`Name: java
+Link: /usr/bin/java
+
+Alternative: /usr/bin/java-21
+X-Test-Vendor: openjdk
+`)
+	assert.ErrorIs(t, err, queryalternatives.ErrUnexpectedKey)
+
+	_, err = queryalternatives.ParseString(`Name: java
+X-Test-Vendor:
+`)
+	var parseErr *queryalternatives.ParseError
+	require.ErrorAs(t, err, &parseErr)
+	assert.Equal(t, "X-Test-Vendor: empty vendor", parseErr.Message)
+	assert.Equal(t, 2, parseErr.Line)
+}
+
+func Test_RegisterAlternativeKey(t *testing.T) {
+	t.Parallel()
+
+	queryalternatives.RegisterAlternativeKey("X-Test-Bonus", func(alt *queryalternatives.Alternative, v string) error {
+		bonus, err := strconv.Atoi(v)
+		if err != nil {
+			return err
+		}
+		alt.Priority += bonus
+		return nil
+	})
+	t.Cleanup(func() { queryalternatives.UnregisterAlternativeKey("X-Test-Bonus") })
+
+	result, err := queryalternatives.ParseString(`Name: java
+Link: /usr/bin/java
+Status: auto
+Best: /usr/bin/java-21
+Value: /usr/bin/java-21
+
+Alternative: /usr/bin/java-21
+Priority: 2111
+X-Test-Bonus: 10
+
+Alternative: /usr/bin/java-8
+Priority: 1081
+`)
+	require.NoError(t, err)
+	require.Len(t, result.Alternatives, 2)
+	assert.Equal(t, 2121, result.Alternatives[0].Priority)
+	assert.Equal(t, 1081, result.Alternatives[1].Priority)
+
+	_, err = queryalternatives.ParseString(`Name: java
+
+Alternative: /usr/bin/java-21
+X-Test-Bonus: many
+`)
+	var parseErr *queryalternatives.ParseError
+	if assert.ErrorAs(t, err, &parseErr) {
+		assert.Equal(t, queryalternatives.CodeUnknown, parseErr.Code)
+		assert.Equal(t, 4, parseErr.Line)
+	}
+}
+
+func Test_RegisterTopLevelKey_Panics(t *testing.T) {
+	t.Parallel()
+
+	set := func(*queryalternatives.Alternatives, string) error { return nil }
+	assert.Panics(t, func() { queryalternatives.RegisterTopLevelKey("Name", set) })
+	assert.Panics(t, func() { queryalternatives.RegisterTopLevelKey("", set) })
+	assert.Panics(t, func() { queryalternatives.RegisterTopLevelKey("X-Test-Nil", nil) })
+	assert.Panics(t, func() {
+		queryalternatives.RegisterAlternativeKey("Priority", func(*queryalternatives.Alternative, string) error { return nil })
+	})
+}
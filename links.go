@@ -0,0 +1,196 @@
+package queryalternatives
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// BrokenSlaveLinks returns the group-level slave links whose symlink on
+// disk does not point at the slave of the selected alternative through
+// /etc/alternatives. A slave link which points anywhere else, for example
+// directly at a candidate, is also broken.
+//
+// readlink is called with the path of each slave link and with the path of
+// its entry in /etc/alternatives, and must return the target of the symlink
+// without resolving it further, like os.Readlink, which is used if readlink
+// is nil. A relative target is resolved against the directory of the
+// symlink. An error matching fs.ErrNotExist means the symlink does not
+// exist, which is correct only if the selected alternative does not provide
+// that slave. Other errors are returned as is.
+func (a *Alternatives) BrokenSlaveLinks(readlink func(string) (string, error)) ([]SlaveLink, error) {
+	if readlink == nil {
+		readlink = os.Readlink
+	}
+	selected := a.selected()
+	if selected == nil {
+		return nil, fmt.Errorf("%s: no alternative is selected", a.Name)
+	}
+
+	result := make([]SlaveLink, 0)
+	for _, slave := range a.GroupSlaveTargets() {
+		expected, provided := selected.Slaves[slave.Link]
+
+		entry := filepath.Join("/etc/alternatives", slave.Link)
+		target, err := readlinkFrom(readlink, slave.Path)
+		if err == nil && target != entry {
+			// The slave link bypasses /etc/alternatives.
+			result = append(result, slave)
+			continue
+		}
+		if err == nil {
+			target, err = readlinkFrom(readlink, entry)
+		}
+		if err != nil {
+			if !errors.Is(err, fs.ErrNotExist) {
+				return nil, err
+			}
+			if provided {
+				result = append(result, slave)
+			}
+			continue
+		}
+		if !provided || target != filepath.Clean(expected) {
+			result = append(result, slave)
+		}
+	}
+	return result, nil
+}
+
+// readlinkFrom returns the target of the symlink path read by readlink.
+// A relative target is resolved against the directory of path.
+func readlinkFrom(readlink func(string) (string, error), path string) (string, error) {
+	target, err := readlink(path)
+	if err != nil {
+		return "", err
+	}
+	if !filepath.IsAbs(target) {
+		target = filepath.Join(filepath.Dir(path), target)
+	}
+	return filepath.Clean(target), nil
+}
+
+// ReadClusterLinks reads the symlinks in dir, which is usually
+// /etc/alternatives, and returns a map from the name of each symlink (the
+// name of a group or a slave) to its target.
+// Entries which are not symlinks, such as README, are ignored.
+//
+// readDir is called with dir and returns its entries. If readDir is nil,
+// os.ReadDir is used.
+//
+// readlink is called with the path of each symlink and returns its target.
+// If readlink is nil, os.Readlink is used. A symlink for which readlink
+// returns an error matching fs.ErrNotExist is assumed to have been removed
+// concurrently and is omitted. Other errors are returned as is.
+func ReadClusterLinks(dir string, readDir func(string) ([]fs.DirEntry, error), readlink func(string) (string, error)) (map[string]string, error) {
+	if readDir == nil {
+		readDir = os.ReadDir
+	}
+	if readlink == nil {
+		readlink = os.Readlink
+	}
+
+	entries, err := readDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make(map[string]string)
+	for _, entry := range entries {
+		if entry.Type()&fs.ModeSymlink == 0 {
+			continue
+		}
+		target, err := readlink(filepath.Join(dir, entry.Name()))
+		if err != nil {
+			if errors.Is(err, fs.ErrNotExist) {
+				continue
+			}
+			return nil, err
+		}
+		result[entry.Name()] = target
+	}
+	return result, nil
+}
+
+// CanSwitchToBest reports whether the best alternative can safely be
+// selected, that is, whether Best is a registered alternative and its path
+// exists.
+//
+// stat is called with the path of the best alternative and returns nil if
+// it exists. If stat is nil, os.Stat is used. An error matching
+// fs.ErrNotExist means the path does not exist. Other errors are returned
+// as is.
+func (a *Alternatives) CanSwitchToBest(stat func(string) error) (bool, error) {
+	if stat == nil {
+		stat = func(path string) error {
+			_, err := os.Stat(path)
+			return err
+		}
+	}
+
+	best, ok := a.BestAlternative()
+	if !ok {
+		return false, nil
+	}
+	if err := stat(best.Path); err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return false, nil
+		}
+		return false, err
+	}
+	return true, nil
+}
+
+// LinkMismatch reports whether the symlink at Link does not point at
+// /etc/alternatives/NAME, which means the generic link bypasses the
+// alternatives system, for example because it points directly at a
+// candidate. A missing link is also a mismatch.
+//
+// readlink is called with Link and must return the target of the symlink
+// without resolving it further, like os.Readlink, which is used if readlink
+// is nil. A relative target is resolved against the directory of Link.
+// An error matching fs.ErrNotExist means the link does not exist. Other
+// errors are returned as is.
+func (a *Alternatives) LinkMismatch(readlink func(string) (string, error)) (bool, error) {
+	if readlink == nil {
+		readlink = os.Readlink
+	}
+	if a.Name == "" || a.Link == "" {
+		return false, fmt.Errorf("%s: missing name or link", a.Name)
+	}
+
+	target, err := readlinkFrom(readlink, a.Link)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return true, nil
+		}
+		return false, err
+	}
+	return target != filepath.Join("/etc/alternatives", a.Name), nil
+}
+
+// ExpectedSlaveLinks returns a map from the path of each group-level slave
+// link, such as "/usr/share/man/man1/java.1.gz", to the path which it should
+// finally resolve to with the current selection. Slaves which the selected
+// alternative does not provide are omitted, since their links should not
+// exist.
+// It fails if no alternative is selected, or if the selected alternative
+// provides a slave which is not declared by the group.
+func (a *Alternatives) ExpectedSlaveLinks() (map[string]string, error) {
+	selected := a.selected()
+	if selected == nil {
+		return nil, fmt.Errorf("%s: no alternative is selected", a.Name)
+	}
+
+	result := make(map[string]string, len(selected.Slaves))
+	for _, slave := range slaveLinks(selected.Slaves) {
+		link, ok := a.Slaves[slave.Link]
+		if !ok {
+			return nil, fmt.Errorf("%s: %s: slave %s is not declared in the group", a.Name, selected.Path, slave.Link)
+		}
+		result[link] = slave.Path
+	}
+	return result, nil
+}
@@ -0,0 +1,395 @@
+package queryalternatives_test
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/kofuk/go-queryalternatives"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeReadlink returns a readlink function which resolves links from links.
+func fakeReadlink(links map[string]string) func(string) (string, error) {
+	return func(path string) (string, error) {
+		target, ok := links[path]
+		if !ok {
+			return "", &fs.PathError{Op: "readlink", Path: path, Err: fs.ErrNotExist}
+		}
+		return target, nil
+	}
+}
+
+// throughAlternatives returns the symlinks which make each slave link of a
+// in targets, a map from the path of a slave link to its final target,
+// point at the target through /etc/alternatives.
+func throughAlternatives(a *queryalternatives.Alternatives, targets map[string]string) map[string]string {
+	links := make(map[string]string)
+	for name, path := range a.Slaves {
+		if target, ok := targets[path]; ok {
+			links[path] = "/etc/alternatives/" + name
+			links["/etc/alternatives/"+name] = target
+		}
+	}
+	return links
+}
+
+func Test_BrokenSlaveLinks(t *testing.T) {
+	t.Parallel()
+
+	a := newJava()
+	a.Slaves["jexec"] = "/usr/bin/jexec"
+	a.Slaves["java.ja.1.gz"] = "/usr/share/man/ja/man1/java.1.gz"
+	a.Alternatives[0].Slaves["jexec"] = "/usr/lib/jvm/java-21-openjdk-amd64/lib/jexec"
+
+	tests := []struct {
+		name     string
+		links    map[string]string
+		expected []queryalternatives.SlaveLink
+	}{
+		{
+			name: "all correct",
+			links: map[string]string{
+				"/usr/share/man/man1/java.1.gz": "/usr/lib/jvm/java-21-openjdk-amd64/man/man1/java.1.gz",
+				"/usr/bin/jexec":                "/usr/lib/jvm/java-21-openjdk-amd64/lib/jexec",
+			},
+			expected: []queryalternatives.SlaveLink{},
+		},
+		{
+			name: "broken",
+			links: map[string]string{
+				"/usr/share/man/man1/java.1.gz":    "/usr/lib/jvm/java-8-openjdk-amd64/jre/man/man1/java.1.gz",
+				"/usr/share/man/ja/man1/java.1.gz": "/usr/lib/jvm/java-8-openjdk-amd64/jre/man/ja/man1/java.1.gz",
+			},
+			expected: []queryalternatives.SlaveLink{
+				{Link: "java.1.gz", Path: "/usr/share/man/man1/java.1.gz"},
+				{Link: "java.ja.1.gz", Path: "/usr/share/man/ja/man1/java.1.gz"},
+				{Link: "jexec", Path: "/usr/bin/jexec"},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			result, err := a.BrokenSlaveLinks(fakeReadlink(throughAlternatives(a, test.links)))
+			assert.NoError(t, err)
+			assert.Equal(t, test.expected, result)
+		})
+	}
+}
+
+func Test_BrokenSlaveLinks_Hops(t *testing.T) {
+	t.Parallel()
+
+	// The man directory of the candidate is a symlink, which must not be
+	// resolved when comparing the target with the slave of the candidate.
+	a := newJava()
+	a.Alternatives[0].Slaves["java.1.gz"] = "/usr/lib/jvm/default-java/man/man1/java.1.gz"
+
+	tests := []struct {
+		name     string
+		links    map[string]string
+		expected []queryalternatives.SlaveLink
+	}{
+		{
+			name: "symlinked directory",
+			links: map[string]string{
+				"/usr/share/man/man1/java.1.gz": "/etc/alternatives/java.1.gz",
+				"/etc/alternatives/java.1.gz":   "/usr/lib/jvm/default-java/man/man1/java.1.gz",
+				"/usr/lib/jvm/default-java":     "java-21-openjdk-amd64",
+			},
+			expected: []queryalternatives.SlaveLink{},
+		},
+		{
+			name: "relative",
+			links: map[string]string{
+				"/usr/share/man/man1/java.1.gz": "../../../../etc/alternatives/java.1.gz",
+				"/etc/alternatives/java.1.gz":   "../../usr/lib/jvm/default-java/man/man1/java.1.gz",
+			},
+			expected: []queryalternatives.SlaveLink{},
+		},
+		{
+			name: "bypassed",
+			links: map[string]string{
+				"/usr/share/man/man1/java.1.gz": "/usr/lib/jvm/default-java/man/man1/java.1.gz",
+			},
+			expected: []queryalternatives.SlaveLink{
+				{Link: "java.1.gz", Path: "/usr/share/man/man1/java.1.gz"},
+			},
+		},
+		{
+			name: "missing entry",
+			links: map[string]string{
+				"/usr/share/man/man1/java.1.gz": "/etc/alternatives/java.1.gz",
+			},
+			expected: []queryalternatives.SlaveLink{
+				{Link: "java.1.gz", Path: "/usr/share/man/man1/java.1.gz"},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			result, err := a.BrokenSlaveLinks(fakeReadlink(test.links))
+			assert.NoError(t, err)
+			assert.Equal(t, test.expected, result)
+		})
+	}
+
+	// os.Readlink is used if readlink is nil.
+	dir := t.TempDir()
+	b := newJava()
+	b.Slaves["java.1.gz"] = filepath.Join(dir, "java.1.gz")
+	delete(b.Alternatives[0].Slaves, "java.1.gz")
+	result, err := b.BrokenSlaveLinks(nil)
+	assert.NoError(t, err)
+	assert.Empty(t, result)
+
+	// A leftover link for a slave which is not provided is broken.
+	require.NoError(t, os.Symlink("/usr/lib/jvm/java-21-openjdk-amd64/man/man1/java.1.gz", filepath.Join(dir, "java.1.gz")))
+	result, err = b.BrokenSlaveLinks(nil)
+	assert.NoError(t, err)
+	assert.Equal(t, []queryalternatives.SlaveLink{{Link: "java.1.gz", Path: filepath.Join(dir, "java.1.gz")}}, result)
+}
+
+func Test_BrokenSlaveLinks_Error(t *testing.T) {
+	t.Parallel()
+
+	none := newJava()
+	none.Value = "none"
+	_, err := none.BrokenSlaveLinks(fakeReadlink(nil))
+	assert.Error(t, err)
+
+	permission := errors.New("permission denied")
+	_, err = newJava().BrokenSlaveLinks(func(string) (string, error) {
+		return "", permission
+	})
+	assert.ErrorIs(t, err, permission)
+}
+
+func Test_ReadClusterLinks(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	require.NoError(t, os.Symlink("/usr/lib/jvm/java-21-openjdk-amd64/bin/java", filepath.Join(dir, "java")))
+	require.NoError(t, os.Symlink("/usr/lib/jvm/java-21-openjdk-amd64/man/man1/java.1.gz", filepath.Join(dir, "java.1.gz")))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "README"), []byte("Please read the update-alternatives(1) man page.\n"), 0o644))
+
+	result, err := queryalternatives.ReadClusterLinks(dir, nil, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string{
+		"java":      "/usr/lib/jvm/java-21-openjdk-amd64/bin/java",
+		"java.1.gz": "/usr/lib/jvm/java-21-openjdk-amd64/man/man1/java.1.gz",
+	}, result)
+
+	// A symlink which disappeared while reading is omitted.
+	result, err = queryalternatives.ReadClusterLinks(dir, nil, fakeReadlink(map[string]string{
+		filepath.Join(dir, "java"): "/usr/lib/jvm/java-8-openjdk-amd64/jre/bin/java",
+	}))
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string{
+		"java": "/usr/lib/jvm/java-8-openjdk-amd64/jre/bin/java",
+	}, result)
+
+	permission := errors.New("permission denied")
+	_, err = queryalternatives.ReadClusterLinks(dir, nil, func(string) (string, error) {
+		return "", permission
+	})
+	assert.ErrorIs(t, err, permission)
+
+	_, err = queryalternatives.ReadClusterLinks(filepath.Join(dir, "nonexistent"), nil, nil)
+	assert.ErrorIs(t, err, fs.ErrNotExist)
+}
+
+func Test_ReadClusterLinks_Fake(t *testing.T) {
+	t.Parallel()
+
+	fsys := fstest.MapFS{
+		"etc/alternatives/java":      {Mode: fs.ModeSymlink},
+		"etc/alternatives/java.1.gz": {Mode: fs.ModeSymlink},
+		"etc/alternatives/README":    {Data: []byte("Please read the update-alternatives(1) man page.\n")},
+	}
+	readDir := func(dir string) ([]fs.DirEntry, error) {
+		return fs.ReadDir(fsys, strings.TrimPrefix(dir, "/"))
+	}
+
+	result, err := queryalternatives.ReadClusterLinks("/etc/alternatives", readDir, fakeReadlink(map[string]string{
+		"/etc/alternatives/java":      "/usr/lib/jvm/java-21-openjdk-amd64/bin/java",
+		"/etc/alternatives/java.1.gz": "/usr/lib/jvm/java-21-openjdk-amd64/man/man1/java.1.gz",
+		"/etc/alternatives/README":    "/nonexistent",
+	}))
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string{
+		"java":      "/usr/lib/jvm/java-21-openjdk-amd64/bin/java",
+		"java.1.gz": "/usr/lib/jvm/java-21-openjdk-amd64/man/man1/java.1.gz",
+	}, result)
+
+	permission := errors.New("permission denied")
+	_, err = queryalternatives.ReadClusterLinks("/etc/alternatives", func(string) ([]fs.DirEntry, error) {
+		return nil, permission
+	}, fakeReadlink(nil))
+	assert.ErrorIs(t, err, permission)
+}
+
+func Test_CanSwitchToBest(t *testing.T) {
+	t.Parallel()
+
+	stat := func(existing ...string) func(string) error {
+		return func(path string) error {
+			for _, e := range existing {
+				if e == path {
+					return nil
+				}
+			}
+			return &fs.PathError{Op: "stat", Path: path, Err: fs.ErrNotExist}
+		}
+	}
+	unregistered := newEditor()
+	unregistered.Best = "/usr/bin/emacs"
+
+	tests := []struct {
+		name     string
+		input    *queryalternatives.Alternatives
+		stat     func(string) error
+		expected bool
+	}{
+		{name: "best exists", input: newEditor(), stat: stat("/usr/bin/vim.basic", "/bin/nano"), expected: true},
+		{name: "best missing", input: newEditor(), stat: stat("/bin/nano")},
+		{name: "best not registered", input: unregistered, stat: stat("/usr/bin/emacs")},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			result, err := test.input.CanSwitchToBest(test.stat)
+			assert.NoError(t, err)
+			assert.Equal(t, test.expected, result)
+		})
+	}
+
+	permission := errors.New("permission denied")
+	_, err := newEditor().CanSwitchToBest(func(string) error {
+		return permission
+	})
+	assert.ErrorIs(t, err, permission)
+
+	dir := t.TempDir()
+	best := filepath.Join(dir, "vim.basic")
+	require.NoError(t, os.WriteFile(best, nil, 0o755))
+	a := newEditor()
+	a.Best = best
+	a.Alternatives[1].Path = best
+	result, err := a.CanSwitchToBest(nil)
+	assert.NoError(t, err)
+	assert.True(t, result)
+}
+
+func Test_LinkMismatch(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		links    map[string]string
+		expected bool
+	}{
+		{
+			name:     "through alternatives",
+			links:    map[string]string{"/usr/bin/java": "/etc/alternatives/java"},
+			expected: false,
+		},
+		{
+			name:     "relative",
+			links:    map[string]string{"/usr/bin/java": "../../etc/alternatives/java"},
+			expected: false,
+		},
+		{
+			name:     "bypassed",
+			links:    map[string]string{"/usr/bin/java": "/usr/lib/jvm/java-21-openjdk-amd64/bin/java"},
+			expected: true,
+		},
+		{
+			name:     "other group",
+			links:    map[string]string{"/usr/bin/java": "/etc/alternatives/javac"},
+			expected: true,
+		},
+		{
+			name:     "missing",
+			links:    map[string]string{},
+			expected: true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			result, err := newJava().LinkMismatch(fakeReadlink(test.links))
+			assert.NoError(t, err)
+			assert.Equal(t, test.expected, result)
+		})
+	}
+
+	permission := errors.New("permission denied")
+	_, err := newJava().LinkMismatch(func(string) (string, error) {
+		return "", permission
+	})
+	assert.ErrorIs(t, err, permission)
+}
+
+func Test_ExpectedSlaveLinks(t *testing.T) {
+	t.Parallel()
+
+	a := newJava()
+	a.Slaves["jexec"] = "/usr/bin/jexec"
+	a.Slaves["java.ja.1.gz"] = "/usr/share/man/ja/man1/java.1.gz"
+	a.Alternatives[0].Slaves["jexec"] = "/usr/lib/jvm/java-21-openjdk-amd64/lib/jexec"
+	a.Alternatives[0].Slaves["java.ja.1.gz"] = "/usr/lib/jvm/java-21-openjdk-amd64/man/ja/man1/java.1.gz"
+
+	result, err := a.ExpectedSlaveLinks()
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string{
+		"/usr/share/man/man1/java.1.gz":    "/usr/lib/jvm/java-21-openjdk-amd64/man/man1/java.1.gz",
+		"/usr/share/man/ja/man1/java.1.gz": "/usr/lib/jvm/java-21-openjdk-amd64/man/ja/man1/java.1.gz",
+		"/usr/bin/jexec":                   "/usr/lib/jvm/java-21-openjdk-amd64/lib/jexec",
+	}, result)
+
+	// Slaves which the selected alternative does not provide are omitted.
+	a.Value = "/usr/lib/jvm/java-8-openjdk-amd64/jre/bin/java"
+	result, err = a.ExpectedSlaveLinks()
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string{
+		"/usr/share/man/man1/java.1.gz": "/usr/lib/jvm/java-8-openjdk-amd64/jre/man/man1/java.1.gz",
+	}, result)
+
+	// The result agrees with BrokenSlaveLinks.
+	a.Value = "/usr/lib/jvm/java-21-openjdk-amd64/bin/java"
+	result, err = a.ExpectedSlaveLinks()
+	require.NoError(t, err)
+	broken, err := a.BrokenSlaveLinks(fakeReadlink(throughAlternatives(a, result)))
+	assert.NoError(t, err)
+	assert.Empty(t, broken)
+}
+
+func Test_ExpectedSlaveLinks_Error(t *testing.T) {
+	t.Parallel()
+
+	none := newJava()
+	none.Value = "none"
+	_, err := none.ExpectedSlaveLinks()
+	assert.Error(t, err)
+
+	undeclared := newJava()
+	undeclared.Alternatives[0].Slaves["jexec"] = "/usr/lib/jvm/java-21-openjdk-amd64/lib/jexec"
+	_, err = undeclared.ExpectedSlaveLinks()
+	assert.Error(t, err)
+}
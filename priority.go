@@ -0,0 +1,179 @@
+package queryalternatives
+
+import (
+	"fmt"
+	"sort"
+)
+
+// recordOriginalOrder remembers the current order of Alternatives as the
+// original order of a.
+func (a *Alternatives) recordOriginalOrder() {
+	a.original = make([]string, 0, len(a.Alternatives))
+	for _, alt := range a.Alternatives {
+		a.original = append(a.original, alt.Path)
+	}
+}
+
+// originalOrder returns the alternatives in the recorded original order.
+// The second result is false if no order is recorded, or if it no longer
+// matches Alternatives because alternatives were added, removed or renamed
+// since.
+func (a *Alternatives) originalOrder() ([]Alternative, bool) {
+	if a.original == nil || len(a.original) != len(a.Alternatives) {
+		return nil, false
+	}
+	index := make(map[string]int, len(a.Alternatives))
+	for i, alt := range a.Alternatives {
+		index[alt.Path] = i
+	}
+	result := make([]Alternative, 0, len(a.Alternatives))
+	for _, path := range a.original {
+		i, ok := index[path]
+		if !ok {
+			return nil, false
+		}
+		delete(index, path)
+		result = append(result, a.Alternatives[i])
+	}
+	return result, true
+}
+
+// SortByPriority sorts Alternatives in place by priority in descending
+// order. Alternatives with the same priority keep their relative order.
+// The order in which the parser returned the alternatives, or for a group
+// which was not parsed, the order before the first sort, is remembered and
+// available from InOriginalOrder. If alternatives were added, removed or
+// renamed since, the order before this sort is remembered instead.
+func (a *Alternatives) SortByPriority() {
+	if _, ok := a.originalOrder(); !ok {
+		a.recordOriginalOrder()
+	}
+	sort.SliceStable(a.Alternatives, func(i, j int) bool {
+		return a.Alternatives[i].Priority > a.Alternatives[j].Priority
+	})
+}
+
+// InOriginalOrder returns a copy of the alternatives in the order they were
+// parsed, even after Alternatives has been sorted by SortByPriority.
+// If alternatives were added, removed or renamed since the order was
+// recorded, or for a group which was neither parsed nor sorted, a copy of
+// Alternatives in its current order is returned.
+func (a *Alternatives) InOriginalOrder() []Alternative {
+	if result, ok := a.originalOrder(); ok {
+		return result
+	}
+	return append(make([]Alternative, 0, len(a.Alternatives)), a.Alternatives...)
+}
+
+// highestPriority returns the alternative with the highest priority, or nil
+// if there are no alternatives. The first one wins if several alternatives
+// have the same priority.
+func (a *Alternatives) highestPriority() *Alternative {
+	result, _ := a.AutoChoice()
+	return result
+}
+
+// bestPath returns Best, or the path of the alternative with the highest
+// priority if Best is empty. It returns an empty string if neither is
+// available.
+func (a *Alternatives) bestPath() string {
+	if a.Best != "" {
+		return a.Best
+	}
+	if alt := a.highestPriority(); alt != nil {
+		return alt.Path
+	}
+	return ""
+}
+
+// alternative returns the registered alternative whose path is path, or nil
+// if there is none.
+func (a *Alternatives) alternative(path string) *Alternative {
+	for i := range a.Alternatives {
+		if a.Alternatives[i].Path == path {
+			return &a.Alternatives[i]
+		}
+	}
+	return nil
+}
+
+// PriorityGap returns the priority of the best alternative minus the
+// priority of the selected one. A positive gap means the selection is
+// behind what auto mode would choose.
+// The second result is false if either the best or the selected
+// alternative cannot be resolved to a registered alternative.
+func (a *Alternatives) PriorityGap() (int, bool) {
+	best, _ := a.BestAlternative()
+	selected := a.selected()
+	if best == nil || selected == nil {
+		return 0, false
+	}
+	return best.Priority - selected.Priority, true
+}
+
+// AutoChoice returns the alternative which auto mode would select: the one
+// with the highest priority. If several alternatives have the highest
+// priority, the first one in Alternatives wins.
+// The second result is false if there are no alternatives.
+func (a *Alternatives) AutoChoice() (*Alternative, bool) {
+	return a.AutoChoiceFunc(nil)
+}
+
+// AutoChoiceFunc is like AutoChoice, but ties among the alternatives with the
+// highest priority are broken by less: the alternative x for which
+// less(x, y) holds for every other candidate y is chosen. If less is nil, or
+// it does not order some candidates, the first one in Alternatives wins.
+func (a *Alternatives) AutoChoiceFunc(less func(x, y Alternative) bool) (*Alternative, bool) {
+	var result *Alternative
+	for i := range a.Alternatives {
+		alt := &a.Alternatives[i]
+		switch {
+		case result == nil, alt.Priority > result.Priority:
+			result = alt
+		case alt.Priority == result.Priority && less != nil && less(*alt, *result):
+			result = alt
+		}
+	}
+	return result, result != nil
+}
+
+// RankOf returns the 1-based position of the alternative path when the
+// alternatives are sorted by priority in descending order, so the
+// alternative with the highest priority is ranked 1. Alternatives with the
+// same priority are ranked in the order they appear in Alternatives, as
+// with SortByPriority and AutoChoice.
+// The second result is false if path is not a registered alternative.
+func (a *Alternatives) RankOf(path string) (int, bool) {
+	target := -1
+	for i, alt := range a.Alternatives {
+		if alt.Path == path {
+			target = i
+			break
+		}
+	}
+	if target < 0 {
+		return 0, false
+	}
+
+	rank := 1
+	priority := a.Alternatives[target].Priority
+	for i, alt := range a.Alternatives {
+		if alt.Priority > priority || alt.Priority == priority && i < target {
+			rank++
+		}
+	}
+	return rank, true
+}
+
+// SelectedNewerThanBest reports whether the selected alternative has a
+// higher priority than Best, which happens when Best was computed before
+// the selected alternative was installed.
+// It fails if either the best or the selected alternative cannot be
+// resolved to a registered alternative.
+func (a *Alternatives) SelectedNewerThanBest() (bool, error) {
+	gap, ok := a.PriorityGap()
+	if !ok {
+		return false, fmt.Errorf("%s: best or selected alternative is not registered", a.Name)
+	}
+	return gap < 0, nil
+}
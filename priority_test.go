@@ -0,0 +1,320 @@
+package queryalternatives_test
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/kofuk/go-queryalternatives"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_SortByPriority_InOriginalOrder(t *testing.T) {
+	t.Parallel()
+
+	a, err := queryalternatives.ParseString(`Name: editor
+Link: /usr/bin/editor
+Status: auto
+Best: /usr/bin/vim.basic
+Value: /usr/bin/vim.basic
+
+Alternative: /bin/ed
+Priority: -100
+
+Alternative: /bin/nano
+Priority: 40
+
+Alternative: /usr/bin/vim.tiny
+Priority: 15
+
+Alternative: /usr/bin/vim.basic
+Priority: 50
+`)
+	require.NoError(t, err)
+
+	paths := func(alts []queryalternatives.Alternative) []string {
+		result := make([]string, 0, len(alts))
+		for _, alt := range alts {
+			result = append(result, alt.Path)
+		}
+		return result
+	}
+	original := []string{"/bin/ed", "/bin/nano", "/usr/bin/vim.tiny", "/usr/bin/vim.basic"}
+
+	assert.Equal(t, original, paths(a.InOriginalOrder()))
+
+	a.SortByPriority()
+	assert.Equal(t, []string{"/usr/bin/vim.basic", "/bin/nano", "/usr/bin/vim.tiny", "/bin/ed"}, paths(a.Alternatives))
+	assert.Equal(t, original, paths(a.InOriginalOrder()))
+
+	// Sorting again must not overwrite the original order.
+	a.SortByPriority()
+	assert.Equal(t, original, paths(a.InOriginalOrder()))
+
+	// The result is a copy.
+	a.InOriginalOrder()[0].Path = "/bin/false"
+	assert.Equal(t, original, paths(a.InOriginalOrder()))
+}
+
+func Test_SortByPriority_Equal(t *testing.T) {
+	t.Parallel()
+
+	a, err := queryalternatives.ParseString(javaQuery)
+	require.NoError(t, err)
+	b, err := queryalternatives.ParseString(javaQuery)
+	require.NoError(t, err)
+
+	// The alternatives are already sorted, so sorting does not change the
+	// group apart from the remembered order.
+	a.SortByPriority()
+	assertEqualGroup(t, b, a)
+	assertEqualGroup(t, newJava(), a)
+}
+
+func Test_InOriginalOrder_RecordedAtParseTime(t *testing.T) {
+	t.Parallel()
+
+	a, err := queryalternatives.ParseString(javaQuery)
+	require.NoError(t, err)
+	expected := slices.Clone(a.Alternatives)
+
+	// The order is recorded by the parser, not by SortByPriority.
+	slices.Reverse(a.Alternatives)
+	assert.Equal(t, expected, a.InOriginalOrder())
+
+	// A group which was not parsed is returned as is until it is sorted.
+	built := newJava()
+	slices.Reverse(built.Alternatives)
+	reversed := slices.Clone(built.Alternatives)
+	assert.Equal(t, reversed, built.InOriginalOrder())
+	built.SortByPriority()
+	assert.Equal(t, reversed, built.InOriginalOrder())
+
+	// A value copy keeps the recorded order.
+	copied := *a
+	assert.Equal(t, expected, copied.InOriginalOrder())
+}
+
+func Test_InOriginalOrder_Changed(t *testing.T) {
+	t.Parallel()
+
+	a, err := queryalternatives.ParseString(`Name: editor
+Link: /usr/bin/editor
+Status: auto
+Best: /bin/nano
+Value: /bin/nano
+
+Alternative: /bin/nano
+Priority: 40
+`)
+	require.NoError(t, err)
+
+	// An alternative added after parsing is not lost.
+	a.Alternatives = append(a.Alternatives, queryalternatives.Alternative{Path: "/usr/bin/vim.basic", Priority: 50})
+	a.SortByPriority()
+	assert.Equal(t, []queryalternatives.Alternative{
+		{Path: "/bin/nano", Priority: 40, Slaves: map[string]string{}},
+		{Path: "/usr/bin/vim.basic", Priority: 50},
+	}, a.InOriginalOrder())
+
+	// Changes to the alternatives themselves are reflected.
+	a.Alternatives[0].Priority = 10
+	assert.Equal(t, 10, a.InOriginalOrder()[1].Priority)
+
+	// A removed alternative makes the recorded order stale, and the current
+	// order is returned.
+	a.Alternatives = a.Alternatives[1:]
+	assert.Equal(t, a.Alternatives, a.InOriginalOrder())
+}
+
+func Test_PriorityGap(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name  string
+		value string
+		best  string
+		gap   int
+		found bool
+	}{
+		{
+			name:  "selected is best",
+			value: "/usr/bin/vim.basic",
+			best:  "/usr/bin/vim.basic",
+			gap:   0,
+			found: true,
+		},
+		{
+			name:  "selected below best",
+			value: "/bin/nano",
+			best:  "/usr/bin/vim.basic",
+			gap:   10,
+			found: true,
+		},
+		{
+			name:  "no selection",
+			value: "none",
+			best:  "/usr/bin/vim.basic",
+		},
+		{
+			name:  "unregistered best",
+			value: "/bin/nano",
+			best:  "/usr/bin/emacs",
+		},
+		{
+			name:  "no best",
+			value: "/bin/nano",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			a := newEditor()
+			a.Value = test.value
+			a.Best = test.best
+
+			gap, found := a.PriorityGap()
+			assert.Equal(t, test.gap, gap)
+			assert.Equal(t, test.found, found)
+		})
+	}
+}
+
+func Test_AutoChoiceFunc(t *testing.T) {
+	t.Parallel()
+
+	a := &queryalternatives.Alternatives{
+		Name: "editor",
+		Alternatives: []queryalternatives.Alternative{
+			{Path: "/usr/bin/vim.tiny", Priority: 15},
+			{Path: "/usr/bin/vim.basic", Priority: 50},
+			{Path: "/usr/local/bin/nvim", Priority: 50},
+			{Path: "/bin/vi", Priority: 50},
+		},
+	}
+
+	tests := []struct {
+		name     string
+		less     func(x, y queryalternatives.Alternative) bool
+		expected string
+	}{
+		{
+			name:     "first wins by default",
+			expected: "/usr/bin/vim.basic",
+		},
+		{
+			name: "alphabetical",
+			less: func(x, y queryalternatives.Alternative) bool {
+				return x.Path < y.Path
+			},
+			expected: "/bin/vi",
+		},
+		{
+			name: "longest path",
+			less: func(x, y queryalternatives.Alternative) bool {
+				return len(x.Path) > len(y.Path)
+			},
+			expected: "/usr/local/bin/nvim",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			result, ok := a.AutoChoiceFunc(test.less)
+			if assert.True(t, ok) {
+				assert.Equal(t, test.expected, result.Path)
+			}
+		})
+	}
+
+	result, ok := a.AutoChoice()
+	if assert.True(t, ok) {
+		assert.Equal(t, "/usr/bin/vim.basic", result.Path)
+	}
+
+	result, ok = (&queryalternatives.Alternatives{}).AutoChoice()
+	assert.False(t, ok)
+	assert.Nil(t, result)
+}
+
+func Test_RankOf(t *testing.T) {
+	t.Parallel()
+
+	a := &queryalternatives.Alternatives{
+		Name: "editor",
+		Alternatives: []queryalternatives.Alternative{
+			{Path: "/bin/ed", Priority: -100},
+			{Path: "/usr/bin/vim.basic", Priority: 50},
+			{Path: "/bin/nano", Priority: 40},
+			{Path: "/usr/local/bin/nvim", Priority: 50},
+			{Path: "/usr/bin/vim.tiny", Priority: 15},
+		},
+	}
+
+	tests := []struct {
+		path     string
+		expected int
+		found    bool
+	}{
+		{path: "/usr/bin/vim.basic", expected: 1, found: true},
+		{path: "/usr/local/bin/nvim", expected: 2, found: true},
+		{path: "/bin/nano", expected: 3, found: true},
+		{path: "/usr/bin/vim.tiny", expected: 4, found: true},
+		{path: "/bin/ed", expected: 5, found: true},
+		{path: "/usr/bin/emacs"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.path, func(t *testing.T) {
+			t.Parallel()
+
+			rank, found := a.RankOf(test.path)
+			assert.Equal(t, test.expected, rank)
+			assert.Equal(t, test.found, found)
+		})
+	}
+}
+
+func Test_SelectedNewerThanBest(t *testing.T) {
+	t.Parallel()
+
+	inverted := newEditor()
+	inverted.Alternatives[0].Priority = 60
+	onBest := newEditor()
+	onBest.Value = onBest.Best
+	none := newEditor()
+	none.Value = "none"
+	noBest := newEditor()
+	noBest.Best = ""
+
+	tests := []struct {
+		name     string
+		input    *queryalternatives.Alternatives
+		expected bool
+		err      bool
+	}{
+		{name: "selected below best", input: newEditor(), expected: false},
+		{name: "selected is best", input: onBest, expected: false},
+		{name: "selected above best", input: inverted, expected: true},
+		{name: "no selection", input: none, err: true},
+		{name: "no best", input: noBest, err: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			result, err := test.input.SelectedNewerThanBest()
+			if test.err {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, test.expected, result)
+		})
+	}
+}
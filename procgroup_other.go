@@ -0,0 +1,8 @@
+//go:build !unix
+
+package queryalternatives
+
+import "os/exec"
+
+// setProcessGroup does nothing on platforms without process groups.
+func setProcessGroup(cmd *exec.Cmd) {}
@@ -0,0 +1,37 @@
+//go:build unix
+
+package queryalternatives_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/kofuk/go-queryalternatives"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_Querier_KillProcessGroup(t *testing.T) {
+	t.Parallel()
+
+	// The subprocess inherits the standard output, so the command cannot
+	// finish before the subprocess is terminated.
+	spawning := filepath.Join(t.TempDir(), "spawning")
+	require.NoError(t, os.WriteFile(spawning, []byte("#!/bin/sh\nsleep 30 &\nwait\n"), 0o755))
+
+	q := &queryalternatives.Querier{
+		Path:             spawning,
+		KillProcessGroup: true,
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	_, err := q.Query(ctx, "java")
+	assert.Error(t, err)
+	assert.Less(t, time.Since(start), 10*time.Second)
+}
@@ -0,0 +1,506 @@
+package queryalternatives
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// Querier runs the `update-alternatives` command.
+// The zero value is ready to use.
+type Querier struct {
+	// Path is the path to the update-alternatives executable.
+	// If empty, "update-alternatives" is looked up in PATH.
+	Path string
+	// Runner runs the prepared command and waits for it to finish.
+	// If nil, cmd.Run is used. This is mainly useful for tests.
+	Runner func(cmd *exec.Cmd) error
+	// AdminDir is passed to update-alternatives as --admindir if non-empty.
+	AdminDir string
+	// AltDir is passed to update-alternatives as --altdir if non-empty.
+	AltDir string
+	// Dir is the working directory of the command.
+	// If empty, the command runs in the current directory.
+	Dir string
+	// Env is a list of additional environment variables in the form
+	// "key=value" for the command. They are appended to the environment of
+	// the current process. The command always runs with LC_MESSAGES=C so
+	// that its errors can be classified by their text; the other locale
+	// categories are left alone. Setting LC_MESSAGES in Env overrides it, and
+	// LC_ALL, in Env or in the environment of the current process, takes
+	// precedence over it; if messages are translated as a result, the
+	// classification relies on the exit status alone.
+	Env []string
+	// MaxOutputBytes limits the number of bytes captured from each of the
+	// standard output and the standard error of the command.
+	// If the command writes more than that, ErrOutputTooLarge is returned.
+	// Zero means no limit.
+	MaxOutputBytes int
+	// DryRun makes the Querier skip commands which modify the system, such
+	// as Set and Auto. Queries are still executed.
+	DryRun bool
+	// OnCommand is called with the command line before each command is
+	// executed, for example for audit logging.
+	OnCommand func(argv []string)
+	// DefaultTimeout limits the time each command may take when the context
+	// passed to the Querier has no deadline. If the context has a deadline,
+	// it is used as is, even if it is later than DefaultTimeout from now.
+	// Zero means no default timeout.
+	DefaultTimeout time.Duration
+	// KillProcessGroup makes the command run in a new process group, and
+	// the whole group is killed when the context is done, so that
+	// subprocesses spawned by the command do not linger.
+	// It only has effect on Unix.
+	KillProcessGroup bool
+	// OnComplete is called after each operation which executes a command,
+	// such as Query, finishes, whether it succeeded or not, with the action
+	// (the first argument after the options, such as "--query", rather than
+	// the name of a group), the time the operation took and the error
+	// returned to the caller, including errors from parsing the output, for
+	// example to record metrics. It complements OnCommand, which is called
+	// before the command is executed.
+	OnComplete func(action string, d time.Duration, err error)
+	// StrictResult makes Query fail with a ParseError of CodeMissingField
+	// if Name or Link of the result is empty. They are always present in
+	// the output of update-alternatives, so an empty one means that the
+	// output was truncated or its format has changed.
+	StrictResult bool
+	// FailOnStderr makes a command which exits successfully but writes to
+	// its standard error, such as a warning, fail with a QueryError whose
+	// Message is the text written and ExitStatus is zero.
+	FailOnStderr bool
+}
+
+// ErrOutputTooLarge is returned when update-alternatives writes more than
+// Querier.MaxOutputBytes bytes.
+var ErrOutputTooLarge = errors.New("output too large")
+
+// limitedBuffer is a buffer which discards everything written beyond max
+// bytes. A max of zero means no limit.
+type limitedBuffer struct {
+	buf      bytes.Buffer
+	max      int
+	exceeded bool
+}
+
+func (b *limitedBuffer) Write(p []byte) (int, error) {
+	if b.max > 0 && b.buf.Len()+len(p) > b.max {
+		// Keep consuming the output so that the command does not block on
+		// a full pipe.
+		b.exceeded = true
+		b.buf.Write(p[:b.max-b.buf.Len()])
+		return len(p), nil
+	}
+	return b.buf.Write(p)
+}
+
+func (b *limitedBuffer) Bytes() []byte {
+	return b.buf.Bytes()
+}
+
+func (b *limitedBuffer) String() string {
+	return b.buf.String()
+}
+
+var defaultQuerier = &Querier{}
+
+type querierKey struct{}
+
+// WithQuerier returns a copy of ctx which carries q.
+// Package-level functions such as Query use the Querier carried by their
+// context, falling back to the default Querier (the zero value) if there is
+// none. Functions which take a *Querier argument use it if it is non-nil,
+// and the one carried by the context otherwise.
+func WithQuerier(ctx context.Context, q *Querier) context.Context {
+	return context.WithValue(ctx, querierKey{}, q)
+}
+
+// querierFrom returns the Querier carried by ctx, or the default Querier.
+func querierFrom(ctx context.Context) *Querier {
+	if q, ok := ctx.Value(querierKey{}).(*Querier); ok && q != nil {
+		return q
+	}
+	return defaultQuerier
+}
+
+func (q *Querier) command(ctx context.Context, args ...string) *exec.Cmd {
+	path := q.Path
+	if path == "" {
+		path = "update-alternatives"
+	}
+
+	var opts []string
+	if q.AdminDir != "" {
+		opts = append(opts, "--admindir", q.AdminDir)
+	}
+	if q.AltDir != "" {
+		opts = append(opts, "--altdir", q.AltDir)
+	}
+
+	cmd := exec.CommandContext(ctx, path, append(opts, args...)...)
+	cmd.Dir = q.Dir
+	cmd.Env = append(append(os.Environ(), "LC_MESSAGES=C"), q.Env...)
+	if q.KillProcessGroup {
+		setProcessGroup(cmd)
+	}
+	return cmd
+}
+
+// Clone returns a copy of q. The copy does not share any mutable state with
+// q, so it can be modified without affecting q even if q is in use by other
+// goroutines.
+func (q *Querier) Clone() *Querier {
+	c := *q
+	if q.Env != nil {
+		c.Env = append([]string(nil), q.Env...)
+	}
+	return &c
+}
+
+// complete calls OnComplete, if set, for the operation action which started
+// at start and returned *err. It is meant to be deferred by the operations
+// with a pointer to their named error result.
+func (q *Querier) complete(action string, start time.Time, err *error) {
+	if q.OnComplete != nil {
+		q.OnComplete(action, time.Since(start), *err)
+	}
+}
+
+// run executes update-alternatives with args and returns its standard output.
+func (q *Querier) run(ctx context.Context, args ...string) (out []byte, err error) {
+	if _, ok := ctx.Deadline(); !ok && q.DefaultTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, q.DefaultTimeout)
+		defer cancel()
+	}
+
+	cmd := q.command(ctx, args...)
+
+	if q.OnCommand != nil {
+		q.OnCommand(append([]string(nil), cmd.Args...))
+	}
+
+	stdout := &limitedBuffer{max: q.MaxOutputBytes}
+	stderr := &limitedBuffer{max: q.MaxOutputBytes}
+	cmd.Stdout = stdout
+	cmd.Stderr = stderr
+
+	if q.Runner != nil {
+		err = q.Runner(cmd)
+	} else {
+		err = cmd.Run()
+	}
+	if stdout.exceeded || stderr.exceeded {
+		return nil, fmt.Errorf("%w: limit is %d bytes", ErrOutputTooLarge, q.MaxOutputBytes)
+	}
+	if err != nil {
+		var exitErr interface{ ExitCode() int }
+		if errors.As(err, &exitErr) {
+			return nil, classifyQueryError(&QueryError{
+				ExitStatus: exitErr.ExitCode(),
+				Message:    strings.TrimSpace(stderr.String()),
+				Args:       cmd.Args,
+			})
+		}
+		return nil, err
+	}
+	if message := strings.TrimSpace(stderr.String()); q.FailOnStderr && message != "" {
+		return nil, &QueryError{
+			Message: message,
+			Args:    cmd.Args,
+		}
+	}
+
+	return stdout.Bytes(), nil
+}
+
+// exitStatusError is the exit status of update-alternatives on any error.
+// update-alternatives does not use distinct exit statuses for different
+// errors; an unknown name also results in this status.
+const exitStatusError = 2
+
+// errorPrefix is the prefix of error messages printed by update-alternatives
+// in the C locale.
+const errorPrefix = "update-alternatives: error:"
+
+// classifyQueryFailure classifies an error of `update-alternatives --query`
+// which was not recognized by its message. Messages are normally in the C
+// locale (see Querier.Env), so this is a last resort for when they are still
+// translated, for example because Env sets the locale: a failure with
+// exitStatusError and a non-empty message none of whose lines is an error in
+// the C locale is considered a NotFoundError, since the name being unknown
+// is the most common reason. An unreadable database or a lack of permission
+// may be misclassified this way. A failure without a message is left as is.
+func classifyQueryFailure(err error) error {
+	queryErr, ok := err.(*QueryError)
+	if !ok || queryErr.ExitStatus != exitStatusError || queryErr.Message == "" {
+		return err
+	}
+	for line := range strings.Lines(queryErr.Message) {
+		if strings.HasPrefix(line, errorPrefix) {
+			return err
+		}
+	}
+	return &NotFoundError{QueryError: *queryErr}
+}
+
+// Query executes the `update-alternatives --query` command and returns the parsed result.
+// If the group does not exist, a NotFoundError is returned. It is detected
+// from the message in the C locale, or from the exit status if the message is
+// translated (see classifyQueryFailure).
+func (q *Querier) Query(ctx context.Context, name string) (result *Alternatives, err error) {
+	defer q.complete("--query", time.Now(), &err)
+
+	out, err := q.run(ctx, "--query", name)
+	if err != nil {
+		return nil, classifyQueryFailure(err)
+	}
+	result, err = NewParser(bytes.NewReader(out)).Parse()
+	if err != nil {
+		return nil, err
+	}
+	if q.StrictResult {
+		if err := checkRequiredFields(result); err != nil {
+			return nil, err
+		}
+	}
+	return result, nil
+}
+
+// checkRequiredFields returns a ParseError if a field of a which is always
+// present in the output of update-alternatives is empty.
+func checkRequiredFields(a *Alternatives) error {
+	var missing string
+	switch {
+	case a.Name == "":
+		missing = "Name"
+	case a.Link == "":
+		missing = "Link"
+	default:
+		return nil
+	}
+	return &ParseError{
+		Code:    CodeMissingField,
+		Message: fmt.Sprintf("missing %s", missing),
+	}
+}
+
+// QueryWithList is like Query, but also returns the paths of the
+// alternatives in the same order as `update-alternatives --list` would.
+// The list is derived from the result of the query without executing
+// --list.
+func (q *Querier) QueryWithList(ctx context.Context, name string) (*Alternatives, []string, error) {
+	alts, err := q.Query(ctx, name)
+	if err != nil {
+		return nil, nil, err
+	}
+	list := make([]string, 0, len(alts.Alternatives))
+	for _, alt := range alts.Alternatives {
+		list = append(list, alt.Path)
+	}
+	return alts, list, nil
+}
+
+// QueryJSON queries the group name and writes it to w as JSON, followed by a
+// newline. If the query fails, the error is returned before anything is
+// written to w.
+func (q *Querier) QueryJSON(ctx context.Context, name string, w io.Writer) error {
+	alts, err := q.Query(ctx, name)
+	if err != nil {
+		return err
+	}
+	return json.NewEncoder(w).Encode(alts)
+}
+
+// Names executes the `update-alternatives --get-selections` command and
+// returns the names of all alternatives groups.
+func (q *Querier) Names(ctx context.Context) (names []string, err error) {
+	defer q.complete("--get-selections", time.Now(), &err)
+
+	out, err := q.run(ctx, "--get-selections")
+	if err != nil {
+		return nil, err
+	}
+
+	names = make([]string, 0)
+	scanner := bufio.NewScanner(bytes.NewReader(out))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 {
+			continue
+		}
+		names = append(names, fields[0])
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return names, nil
+}
+
+// QueryMany queries each of names in order.
+// Groups which could not be queried are omitted from the result, and
+// their errors are joined into the returned error, so the result is
+// usable even if the error is non-nil.
+func (q *Querier) QueryMany(ctx context.Context, names []string) ([]*Alternatives, error) {
+	result := make([]*Alternatives, 0, len(names))
+	var errs []error
+	for _, name := range names {
+		alts, err := q.Query(ctx, name)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", name, err))
+			continue
+		}
+		result = append(result, alts)
+	}
+	return result, errors.Join(errs...)
+}
+
+// QueryByLink returns the alternatives group whose generic link is link.
+// If no group uses link, a NotFoundError is returned.
+func (q *Querier) QueryByLink(ctx context.Context, link string) (*Alternatives, error) {
+	names, err := q.Names(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, name := range names {
+		alts, err := q.Query(ctx, name)
+		if err != nil {
+			if errors.Is(err, ErrNotFound) {
+				// The group was removed after it was listed.
+				continue
+			}
+			return nil, err
+		}
+		if alts.Link == link {
+			return alts, nil
+		}
+	}
+
+	return nil, &NotFoundError{
+		QueryError: QueryError{
+			Message: "no alternatives for link " + link,
+		},
+	}
+}
+
+// BestPath returns the path to the best alternative of the group name.
+// If update-alternatives does not report the best alternative, the
+// alternative with the highest priority is returned instead.
+// If the group does not exist, a NotFoundError is returned.
+func (q *Querier) BestPath(ctx context.Context, name string) (string, error) {
+	alts, err := q.Query(ctx, name)
+	if err != nil {
+		return "", err
+	}
+	best := alts.bestPath()
+	if best == "" {
+		return "", fmt.Errorf("%s: no alternatives available", name)
+	}
+	return best, nil
+}
+
+// IsSelectedPath queries the group name and reports whether the currently
+// selected alternative is path, that is, whether Value equals path.
+// A NotFoundError is returned if the group does not exist.
+func (q *Querier) IsSelectedPath(ctx context.Context, name, path string) (bool, error) {
+	alts, err := q.Query(ctx, name)
+	if err != nil {
+		return false, err
+	}
+	return alts.Value == path, nil
+}
+
+// Names returns the names of all alternatives groups using the Querier
+// carried by ctx (see WithQuerier).
+func Names(ctx context.Context) ([]string, error) {
+	return querierFrom(ctx).Names(ctx)
+}
+
+// QueryJSON queries the group name and writes it to w as JSON using the
+// Querier carried by ctx (see WithQuerier).
+// See Querier.QueryJSON for details.
+func QueryJSON(ctx context.Context, name string, w io.Writer) error {
+	return querierFrom(ctx).QueryJSON(ctx, name, w)
+}
+
+// QueryMany queries each of names using the Querier carried by ctx
+// (see WithQuerier).
+// See Querier.QueryMany for details.
+func QueryMany(ctx context.Context, names []string) ([]*Alternatives, error) {
+	return querierFrom(ctx).QueryMany(ctx, names)
+}
+
+// QueryByLink returns the alternatives group whose generic link is link
+// using the Querier carried by ctx (see WithQuerier).
+func QueryByLink(ctx context.Context, link string) (*Alternatives, error) {
+	return querierFrom(ctx).QueryByLink(ctx, link)
+}
+
+// BestPath returns the path to the best alternative of the group name
+// using the Querier carried by ctx (see WithQuerier).
+// See Querier.BestPath for details.
+func BestPath(ctx context.Context, name string) (string, error) {
+	return querierFrom(ctx).BestPath(ctx, name)
+}
+
+// IsSelectedPath reports whether the currently selected alternative of the
+// group name is path using the Querier carried by ctx (see WithQuerier).
+// See Querier.IsSelectedPath for details.
+func IsSelectedPath(ctx context.Context, name, path string) (bool, error) {
+	return querierFrom(ctx).IsSelectedPath(ctx, name, path)
+}
+
+// Version executes `update-alternatives --version` and returns the version
+// number in the first line of its output, such as "1.21.22" for
+// "Debian update-alternatives version 1.21.22.".
+// If q is nil, the Querier carried by ctx is used (see WithQuerier).
+// A QueryError is returned if the command fails or the first line does not
+// contain a version number.
+func Version(ctx context.Context, q *Querier) (version string, err error) {
+	if q == nil {
+		q = querierFrom(ctx)
+	}
+	defer q.complete("--version", time.Now(), &err)
+
+	out, err := q.run(ctx, "--version")
+	if err != nil {
+		return "", err
+	}
+	first, _, _ := strings.Cut(string(out), "\n")
+	m := versionRe.FindStringSubmatch(strings.TrimSpace(first))
+	if m == nil {
+		return "", &QueryError{
+			Message: fmt.Sprintf("unexpected version output: %q", first),
+		}
+	}
+	return m[1], nil
+}
+
+// versionRe matches the first line of the output of --version.
+var versionRe = regexp.MustCompile(`(?i)\bversion\s+v?(\d[\w.+~:-]*?)\.?$`)
+
+// Inventory discovers all alternatives groups and queries each of them.
+// If q is nil, the Querier carried by ctx is used (see WithQuerier).
+// Errors for individual groups are joined into the returned error, and
+// the groups which were queried successfully are still returned.
+func Inventory(ctx context.Context, q *Querier) ([]*Alternatives, error) {
+	if q == nil {
+		q = querierFrom(ctx)
+	}
+
+	names, err := q.Names(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return q.QueryMany(ctx, names)
+}
@@ -0,0 +1,854 @@
+package queryalternatives_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kofuk/go-queryalternatives"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type exitError int
+
+func (e exitError) Error() string {
+	return fmt.Sprintf("exit status %d", int(e))
+}
+
+func (e exitError) ExitCode() int {
+	return int(e)
+}
+
+type fakeCommand struct {
+	stdout string
+	stderr string
+	exit   int
+}
+
+// fakeRunner returns a runner which replies to each command line
+// (without the program name) with the corresponding fakeCommand.
+func fakeRunner(commands map[string]fakeCommand) func(cmd *exec.Cmd) error {
+	return func(cmd *exec.Cmd) error {
+		c, ok := commands[strings.Join(cmd.Args[1:], " ")]
+		if !ok {
+			return fmt.Errorf("unexpected command: %v", cmd.Args)
+		}
+		io.WriteString(cmd.Stdout, c.stdout)
+		io.WriteString(cmd.Stderr, c.stderr)
+		if c.exit != 0 {
+			return exitError(c.exit)
+		}
+		return nil
+	}
+}
+
+const (
+	javaQuery = `Name: java
+Link: /usr/bin/java
+Slaves:
+ java.1.gz /usr/share/man/man1/java.1.gz
+Status: auto
+Best: /usr/lib/jvm/java-21-openjdk-amd64/bin/java
+Value: /usr/lib/jvm/java-21-openjdk-amd64/bin/java
+
+Alternative: /usr/lib/jvm/java-21-openjdk-amd64/bin/java
+Priority: 2111
+Slaves:
+ java.1.gz /usr/lib/jvm/java-21-openjdk-amd64/man/man1/java.1.gz
+
+Alternative: /usr/lib/jvm/java-8-openjdk-amd64/jre/bin/java
+Priority: 1081
+Slaves:
+ java.1.gz /usr/lib/jvm/java-8-openjdk-amd64/jre/man/man1/java.1.gz
+`
+	editorQuery = `Name: editor
+Link: /usr/bin/editor
+Status: manual
+Best: /usr/bin/vim.basic
+Value: /bin/nano
+
+Alternative: /bin/nano
+Priority: 40
+
+Alternative: /usr/bin/vim.basic
+Priority: 50
+`
+	selections = `editor                         manual   /bin/nano
+java                           auto     /usr/lib/jvm/java-21-openjdk-amd64/bin/java
+missing                        auto     /usr/bin/missing
+`
+)
+
+func Test_Querier_Query(t *testing.T) {
+	t.Parallel()
+
+	q := &queryalternatives.Querier{
+		Runner: fakeRunner(map[string]fakeCommand{
+			"--query java": {stdout: javaQuery},
+			"--query nosuch": {
+				stderr: "update-alternatives: error: no alternatives for nosuch\n",
+				exit:   2,
+			},
+		}),
+	}
+
+	result, err := q.Query(context.Background(), "java")
+	require.NoError(t, err)
+	assert.Equal(t, "java", result.Name)
+	assert.Len(t, result.Alternatives, 2)
+
+	result, err = q.Query(context.Background(), "nosuch")
+	assert.Nil(t, result)
+	var queryErr *queryalternatives.QueryError
+	require.ErrorAs(t, err, &queryErr)
+	assert.Equal(t, 2, queryErr.ExitStatus)
+	assert.Equal(t, "update-alternatives: error: no alternatives for nosuch", queryErr.Message)
+}
+
+func Test_Querier_Query_StrictResult(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name   string
+		stdout string
+		strict bool
+		err    bool
+	}{
+		{name: "complete", stdout: javaQuery, strict: true},
+		{name: "empty", stdout: "", strict: true, err: true},
+		{name: "name only", stdout: "Name: java\n", strict: true, err: true},
+		{name: "link only", stdout: "Link: /usr/bin/java\n\n", strict: true, err: true},
+		{name: "truncated without strict", stdout: "Name: java\n", strict: false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			q := &queryalternatives.Querier{
+				Runner: fakeRunner(map[string]fakeCommand{
+					"--query java": {stdout: test.stdout},
+				}),
+				StrictResult: test.strict,
+			}
+			result, err := q.Query(context.Background(), "java")
+			if !test.err {
+				assert.NoError(t, err)
+				assert.NotNil(t, result)
+				return
+			}
+			assert.Nil(t, result)
+			assert.ErrorIs(t, err, queryalternatives.ErrMissingField)
+			var parseErr *queryalternatives.ParseError
+			if assert.ErrorAs(t, err, &parseErr) {
+				assert.Equal(t, queryalternatives.CodeMissingField, parseErr.Code)
+			}
+		})
+	}
+}
+
+func Test_Querier_QueryWithList(t *testing.T) {
+	t.Parallel()
+
+	calls := 0
+	runner := fakeRunner(map[string]fakeCommand{
+		"--query java": {stdout: javaQuery},
+		"--query nosuch": {
+			stderr: "update-alternatives: error: no alternatives for nosuch\n",
+			exit:   2,
+		},
+	})
+	q := &queryalternatives.Querier{
+		Runner: func(cmd *exec.Cmd) error {
+			calls++
+			return runner(cmd)
+		},
+	}
+
+	result, list, err := q.QueryWithList(context.Background(), "java")
+	require.NoError(t, err)
+	assert.Equal(t, "java", result.Name)
+	assert.Equal(t, []string{
+		"/usr/lib/jvm/java-21-openjdk-amd64/bin/java",
+		"/usr/lib/jvm/java-8-openjdk-amd64/jre/bin/java",
+	}, list)
+	assert.Equal(t, 1, calls)
+
+	result, list, err = q.QueryWithList(context.Background(), "nosuch")
+	assert.ErrorIs(t, err, queryalternatives.ErrNotFound)
+	assert.Nil(t, result)
+	assert.Nil(t, list)
+}
+
+func Test_QueryJSON(t *testing.T) {
+	t.Parallel()
+
+	ctx := queryalternatives.WithQuerier(context.Background(), &queryalternatives.Querier{
+		Runner: fakeRunner(map[string]fakeCommand{
+			"--query java": {stdout: javaQuery},
+			"--query nosuch": {
+				stderr: "update-alternatives: error: no alternatives for nosuch\n",
+				exit:   2,
+			},
+		}),
+	})
+
+	var buf bytes.Buffer
+	require.NoError(t, queryalternatives.QueryJSON(ctx, "java", &buf))
+	expected, err := queryalternatives.Query(ctx, "java")
+	require.NoError(t, err)
+	var decoded queryalternatives.Alternatives
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &decoded))
+	assertEqualGroup(t, expected, &decoded)
+	assert.True(t, strings.HasSuffix(buf.String(), "}\n"))
+
+	buf.Reset()
+	err = queryalternatives.QueryJSON(ctx, "nosuch", &buf)
+	assert.ErrorIs(t, err, queryalternatives.ErrNotFound)
+	assert.Zero(t, buf.Len())
+}
+
+func Test_Querier_Names(t *testing.T) {
+	t.Parallel()
+
+	q := &queryalternatives.Querier{
+		Runner: fakeRunner(map[string]fakeCommand{
+			"--get-selections": {stdout: selections},
+		}),
+	}
+
+	names, err := q.Names(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, []string{"editor", "java", "missing"}, names)
+}
+
+func Test_Inventory(t *testing.T) {
+	t.Parallel()
+
+	q := &queryalternatives.Querier{
+		Runner: fakeRunner(map[string]fakeCommand{
+			"--get-selections": {stdout: selections},
+			"--query editor":   {stdout: editorQuery},
+			"--query java":     {stdout: javaQuery},
+			"--query missing": {
+				stderr: "update-alternatives: error: no alternatives for missing\n",
+				exit:   2,
+			},
+		}),
+	}
+
+	groups, err := queryalternatives.Inventory(context.Background(), q)
+	assert.ErrorContains(t, err, "missing: ")
+	var queryErr *queryalternatives.QueryError
+	assert.ErrorAs(t, err, &queryErr)
+
+	require.Len(t, groups, 2)
+	assert.Equal(t, "editor", groups[0].Name)
+	assert.Equal(t, "java", groups[1].Name)
+}
+
+func Test_Inventory_NamesError(t *testing.T) {
+	t.Parallel()
+
+	q := &queryalternatives.Querier{
+		Runner: fakeRunner(map[string]fakeCommand{
+			"--get-selections": {stderr: "boom\n", exit: 1},
+		}),
+	}
+
+	groups, err := queryalternatives.Inventory(context.Background(), q)
+	assert.Error(t, err)
+	assert.Nil(t, groups)
+}
+
+func Test_Querier_Query_ErrorClassification(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name          string
+		stderr        string
+		notFound      bool
+		permission    bool
+		expectedExact bool
+	}{
+		{
+			name:     "unknown group",
+			stderr:   "update-alternatives: error: no alternatives for java\n",
+			notFound: true,
+		},
+		{
+			name:     "unknown group in other locale",
+			stderr:   "update-alternatives: Fehler: keine Alternativen für java\n",
+			notFound: true,
+		},
+		{
+			name:       "permission denied",
+			stderr:     "update-alternatives: error: unable to create file '/var/lib/dpkg/alternatives/java.dpkg-tmp': Permission denied\n",
+			permission: true,
+		},
+		{
+			name:          "other error",
+			stderr:        "update-alternatives: error: alternative path /nonexistent doesn't exist\n",
+			expectedExact: true,
+		},
+		{
+			name:          "no message",
+			stderr:        "",
+			expectedExact: true,
+		},
+		{
+			name:          "error after warning",
+			stderr:        "update-alternatives: warning: forcing reinstallation of alternative /usr/bin/vim.basic because link group editor is broken\nupdate-alternatives: error: cannot stat file '/usr/bin/vim.basic': Input/output error\n",
+			expectedExact: true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			q := &queryalternatives.Querier{
+				Runner: fakeRunner(map[string]fakeCommand{
+					"--query java": {stderr: test.stderr, exit: 2},
+				}),
+			}
+
+			_, err := q.Query(context.Background(), "java")
+			require.Error(t, err)
+
+			assert.Equal(t, test.notFound, errors.Is(err, queryalternatives.ErrNotFound))
+			assert.Equal(t, test.permission, errors.Is(err, queryalternatives.ErrPermission))
+
+			var notFoundErr *queryalternatives.NotFoundError
+			assert.Equal(t, test.notFound, errors.As(err, &notFoundErr))
+			var permissionErr *queryalternatives.PermissionError
+			assert.Equal(t, test.permission, errors.As(err, &permissionErr))
+
+			var queryErr *queryalternatives.QueryError
+			require.ErrorAs(t, err, &queryErr)
+			assert.Equal(t, 2, queryErr.ExitStatus)
+			assert.Equal(t, strings.TrimSpace(test.stderr), queryErr.Message)
+			if test.expectedExact {
+				assert.Same(t, queryErr, err)
+			}
+		})
+	}
+}
+
+func Test_Querier_QueryByLink(t *testing.T) {
+	t.Parallel()
+
+	q := &queryalternatives.Querier{
+		Runner: fakeRunner(map[string]fakeCommand{
+			"--get-selections": {stdout: selections},
+			"--query editor":   {stdout: editorQuery},
+			"--query java":     {stdout: javaQuery},
+			"--query missing": {
+				stderr: "update-alternatives: error: no alternatives for missing\n",
+				exit:   2,
+			},
+		}),
+	}
+
+	result, err := q.QueryByLink(context.Background(), "/usr/bin/java")
+	require.NoError(t, err)
+	assert.Equal(t, "java", result.Name)
+
+	result, err = q.QueryByLink(context.Background(), "/usr/bin/javac")
+	assert.Nil(t, result)
+	assert.ErrorIs(t, err, queryalternatives.ErrNotFound)
+	var notFoundErr *queryalternatives.NotFoundError
+	assert.ErrorAs(t, err, &notFoundErr)
+}
+
+func Test_Querier_FailOnStderr(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	warning := "update-alternatives: warning: forcing reinstallation of alternative /usr/lib/jvm/java-21-openjdk-amd64/bin/java because link group java is broken"
+	warns := filepath.Join(dir, "warns")
+	require.NoError(t, os.WriteFile(warns, []byte("#!/bin/sh\ncat <<'EOF'\n"+javaQuery+"EOF\necho '"+warning+"' >&2\n"), 0o755))
+	quiet := filepath.Join(dir, "quiet")
+	require.NoError(t, os.WriteFile(quiet, []byte("#!/bin/sh\ncat <<'EOF'\n"+javaQuery+"EOF\n"), 0o755))
+
+	tests := []struct {
+		name         string
+		path         string
+		failOnStderr bool
+		wantErr      bool
+	}{
+		{name: "warning", path: warns, failOnStderr: true, wantErr: true},
+		{name: "warning ignored", path: warns, failOnStderr: false},
+		{name: "quiet", path: quiet, failOnStderr: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			q := &queryalternatives.Querier{
+				Path:         test.path,
+				FailOnStderr: test.failOnStderr,
+			}
+			result, err := q.Query(context.Background(), "java")
+			if !test.wantErr {
+				assert.NoError(t, err)
+				if assert.NotNil(t, result) {
+					assert.Equal(t, "java", result.Name)
+				}
+				return
+			}
+			assert.Nil(t, result)
+			var queryErr *queryalternatives.QueryError
+			require.ErrorAs(t, err, &queryErr)
+			assert.Equal(t, 0, queryErr.ExitStatus)
+			assert.Equal(t, warning, queryErr.Message)
+			assert.Equal(t, []string{test.path, "--query", "java"}, queryErr.Args)
+		})
+	}
+}
+
+func Test_Querier_MaxOutputBytes(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	oversized := filepath.Join(dir, "oversized")
+	require.NoError(t, os.WriteFile(oversized, []byte("#!/bin/sh\nyes 'Name: java' | head -c 1000000\n"), 0o755))
+	noisy := filepath.Join(dir, "noisy")
+	require.NoError(t, os.WriteFile(noisy, []byte("#!/bin/sh\nyes 'error' | head -c 1000000 >&2\nexit 2\n"), 0o755))
+	small := filepath.Join(dir, "small")
+	require.NoError(t, os.WriteFile(small, []byte("#!/bin/sh\necho 'Name: java'\n"), 0o755))
+
+	tests := []struct {
+		name           string
+		path           string
+		maxOutputBytes int
+		tooLarge       bool
+	}{
+		{name: "stdout exceeds limit", path: oversized, maxOutputBytes: 4096, tooLarge: true},
+		{name: "stderr exceeds limit", path: noisy, maxOutputBytes: 4096, tooLarge: true},
+		{name: "within limit", path: small, maxOutputBytes: 4096},
+		{name: "unlimited", path: oversized, maxOutputBytes: 0},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			q := &queryalternatives.Querier{
+				Path:           test.path,
+				MaxOutputBytes: test.maxOutputBytes,
+			}
+			_, err := q.Query(context.Background(), "java")
+			if test.tooLarge {
+				assert.ErrorIs(t, err, queryalternatives.ErrOutputTooLarge)
+			} else {
+				assert.NotErrorIs(t, err, queryalternatives.ErrOutputTooLarge)
+			}
+		})
+	}
+}
+
+func Test_Querier_DefaultTimeout(t *testing.T) {
+	t.Parallel()
+
+	slow := filepath.Join(t.TempDir(), "slow")
+	require.NoError(t, os.WriteFile(slow, []byte("#!/bin/sh\nexec sleep 0.5\n"), 0o755))
+
+	q := &queryalternatives.Querier{
+		Path:           slow,
+		DefaultTimeout: 50 * time.Millisecond,
+	}
+
+	t.Run("without deadline", func(t *testing.T) {
+		t.Parallel()
+
+		_, err := q.Query(context.Background(), "java")
+		assert.Error(t, err)
+	})
+
+	t.Run("explicit deadline wins", func(t *testing.T) {
+		t.Parallel()
+
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		_, err := q.Query(ctx, "java")
+		assert.NoError(t, err)
+	})
+}
+
+func Test_Querier_Options(t *testing.T) {
+	t.Parallel()
+
+	var args, env []string
+	var dir string
+	q := &queryalternatives.Querier{
+		AdminDir: "/mnt/image/var/lib/dpkg/alternatives",
+		AltDir:   "/mnt/image/etc/alternatives",
+		Dir:      "/mnt/image",
+		Env:      []string{"LC_ALL=C"},
+		Runner: func(cmd *exec.Cmd) error {
+			args = cmd.Args[1:]
+			env = cmd.Env
+			dir = cmd.Dir
+			io.WriteString(cmd.Stdout, javaQuery)
+			return nil
+		},
+	}
+
+	_, err := q.Query(context.Background(), "java")
+	require.NoError(t, err)
+	assert.Equal(t, []string{
+		"--admindir", "/mnt/image/var/lib/dpkg/alternatives",
+		"--altdir", "/mnt/image/etc/alternatives",
+		"--query", "java",
+	}, args)
+	assert.Contains(t, env, "LC_ALL=C")
+	assert.Contains(t, env, "LC_MESSAGES=C")
+	assert.Equal(t, "/mnt/image", dir)
+
+	q.Dir = ""
+	_, err = q.Query(context.Background(), "java")
+	require.NoError(t, err)
+	assert.Empty(t, dir)
+
+	// Messages are in the C locale even without Env, and the rest of the
+	// environment is passed as is.
+	q.Env = nil
+	_, err = q.Query(context.Background(), "java")
+	require.NoError(t, err)
+	assert.Equal(t, append(os.Environ(), "LC_MESSAGES=C"), env)
+}
+
+func Test_Querier_Clone(t *testing.T) {
+	t.Parallel()
+
+	q := &queryalternatives.Querier{
+		AdminDir: "/var/lib/dpkg/alternatives",
+		Env:      []string{"LC_ALL=C"},
+	}
+
+	c := q.Clone()
+	assert.Equal(t, q, c)
+	assert.NotSame(t, q, c)
+
+	c.AdminDir = "/mnt/image/var/lib/dpkg/alternatives"
+	c.DryRun = true
+	c.Env[0] = "LC_ALL=ja_JP.UTF-8"
+	c.Env = append(c.Env, "LANG=C")
+
+	assert.Equal(t, &queryalternatives.Querier{
+		AdminDir: "/var/lib/dpkg/alternatives",
+		Env:      []string{"LC_ALL=C"},
+	}, q)
+}
+
+func Test_Querier_OnCommand(t *testing.T) {
+	t.Parallel()
+
+	var commands [][]string
+	q := &queryalternatives.Querier{
+		Path:     "/usr/sbin/update-alternatives",
+		AdminDir: "/tmp/admin",
+		OnCommand: func(argv []string) {
+			commands = append(commands, argv)
+		},
+		Runner: fakeRunner(map[string]fakeCommand{
+			"--admindir /tmp/admin --query java": {stdout: javaQuery},
+			"--admindir /tmp/admin --query nosuch": {
+				stderr: "update-alternatives: error: no alternatives for nosuch\n",
+				exit:   2,
+			},
+		}),
+	}
+
+	_, err := q.Query(context.Background(), "java")
+	require.NoError(t, err)
+	_, err = q.Query(context.Background(), "nosuch")
+
+	var queryErr *queryalternatives.QueryError
+	require.ErrorAs(t, err, &queryErr)
+	assert.Equal(t, []string{"/usr/sbin/update-alternatives", "--admindir", "/tmp/admin", "--query", "nosuch"}, queryErr.Args)
+
+	assert.Equal(t, [][]string{
+		{"/usr/sbin/update-alternatives", "--admindir", "/tmp/admin", "--query", "java"},
+		{"/usr/sbin/update-alternatives", "--admindir", "/tmp/admin", "--query", "nosuch"},
+	}, commands)
+}
+
+func Test_Querier_OnComplete(t *testing.T) {
+	t.Parallel()
+
+	type completion struct {
+		action string
+		d      time.Duration
+		err    error
+	}
+	var completions []completion
+	runner := fakeRunner(map[string]fakeCommand{
+		"--admindir /tmp/admin --query java":                                              {stdout: javaQuery},
+		"--admindir /tmp/admin --set java /usr/lib/jvm/java-8-openjdk-amd64/jre/bin/java": {},
+		"--admindir /tmp/admin --query nosuch": {
+			stderr: "update-alternatives: error: no alternatives for nosuch\n",
+			exit:   2,
+		},
+	})
+	q := &queryalternatives.Querier{
+		AdminDir: "/tmp/admin",
+		OnComplete: func(action string, d time.Duration, err error) {
+			completions = append(completions, completion{action: action, d: d, err: err})
+		},
+		Runner: func(cmd *exec.Cmd) error {
+			time.Sleep(time.Millisecond)
+			return runner(cmd)
+		},
+	}
+
+	_, err := q.Query(context.Background(), "java")
+	require.NoError(t, err)
+	_, err = q.Query(context.Background(), "nosuch")
+	require.Error(t, err)
+	err = q.Set(context.Background(), "java", "/usr/lib/jvm/java-8-openjdk-amd64/jre/bin/java")
+	require.NoError(t, err)
+
+	q.Runner = nil
+	q.Path = "/nonexistent/update-alternatives"
+	_, err = q.Names(context.Background())
+	require.Error(t, err)
+
+	require.Len(t, completions, 4)
+	for _, c := range completions {
+		assert.Positive(t, c.d)
+	}
+	assert.Equal(t, "--query", completions[0].action)
+	assert.NoError(t, completions[0].err)
+	assert.Equal(t, "--query", completions[1].action)
+	assert.ErrorIs(t, completions[1].err, queryalternatives.ErrNotFound)
+	assert.Equal(t, "--set", completions[2].action)
+	assert.NoError(t, completions[2].err)
+	assert.Equal(t, "--get-selections", completions[3].action)
+	assert.ErrorIs(t, completions[3].err, fs.ErrNotExist)
+}
+
+func Test_Querier_OnComplete_FinalError(t *testing.T) {
+	t.Parallel()
+
+	var errs []error
+	q := &queryalternatives.Querier{
+		Runner: fakeRunner(map[string]fakeCommand{
+			"--query broken":    {stdout: "Name: broken\nStatus: sideways\n"},
+			"--query truncated": {stdout: "Name: truncated\n"},
+			"--query nosuch": {
+				stderr: "update-alternatives: Fehler: keine Alternativen für nosuch\n",
+				exit:   2,
+			},
+		}),
+		StrictResult: true,
+		OnComplete: func(action string, d time.Duration, err error) {
+			errs = append(errs, err)
+		},
+	}
+
+	for _, name := range []string{"broken", "truncated", "nosuch"} {
+		_, err := q.Query(context.Background(), name)
+		require.Error(t, err)
+	}
+
+	require.Len(t, errs, 3)
+	assert.ErrorIs(t, errs[0], queryalternatives.ErrInvalidStatus)
+	assert.ErrorIs(t, errs[1], queryalternatives.ErrMissingField)
+	var notFoundErr *queryalternatives.NotFoundError
+	assert.ErrorAs(t, errs[2], &notFoundErr)
+}
+
+func Test_Querier_BestPath(t *testing.T) {
+	t.Parallel()
+
+	noBest := strings.Replace(editorQuery, "Best: /usr/bin/vim.basic\n", "", 1)
+	empty := "Name: editor\nLink: /usr/bin/editor\nStatus: auto\nValue: none\n"
+
+	tests := []struct {
+		name     string
+		output   string
+		expected string
+		wantErr  bool
+	}{
+		{name: "best reported", output: javaQuery, expected: "/usr/lib/jvm/java-21-openjdk-amd64/bin/java"},
+		{name: "best not reported", output: noBest, expected: "/usr/bin/vim.basic"},
+		{name: "no alternatives", output: empty, wantErr: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			q := &queryalternatives.Querier{
+				Runner: fakeRunner(map[string]fakeCommand{
+					"--query group": {stdout: test.output},
+				}),
+			}
+			result, err := q.BestPath(context.Background(), "group")
+			if test.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, test.expected, result)
+		})
+	}
+
+	q := &queryalternatives.Querier{
+		Runner: fakeRunner(map[string]fakeCommand{
+			"--query nosuch": {
+				stderr: "update-alternatives: error: no alternatives for nosuch\n",
+				exit:   2,
+			},
+		}),
+	}
+	_, err := q.BestPath(context.Background(), "nosuch")
+	var notFoundErr *queryalternatives.NotFoundError
+	assert.ErrorAs(t, err, &notFoundErr)
+}
+
+func Test_IsSelectedPath(t *testing.T) {
+	t.Parallel()
+
+	ctx := queryalternatives.WithQuerier(context.Background(), &queryalternatives.Querier{
+		Runner: fakeRunner(map[string]fakeCommand{
+			"--query editor": {stdout: editorQuery},
+			"--query nosuch": {
+				stderr: "update-alternatives: error: no alternatives for nosuch\n",
+				exit:   2,
+			},
+		}),
+	})
+
+	tests := []struct {
+		name     string
+		group    string
+		path     string
+		expected bool
+		wantErr  bool
+	}{
+		{name: "matching", group: "editor", path: "/bin/nano", expected: true},
+		{name: "best but not selected", group: "editor", path: "/usr/bin/vim.basic", expected: false},
+		{name: "none", group: "editor", path: "none", expected: false},
+		{name: "unknown group", group: "nosuch", path: "/bin/nano", wantErr: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			result, err := queryalternatives.IsSelectedPath(ctx, test.group, test.path)
+			if test.wantErr {
+				var notFoundErr *queryalternatives.NotFoundError
+				assert.ErrorAs(t, err, &notFoundErr)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, test.expected, result)
+		})
+	}
+}
+
+func Test_WithQuerier(t *testing.T) {
+	t.Parallel()
+
+	fromContext := &queryalternatives.Querier{
+		Runner: fakeRunner(map[string]fakeCommand{
+			"--get-selections": {stdout: "java                           auto     /usr/lib/jvm/java-21-openjdk-amd64/bin/java\n"},
+			"--query java":     {stdout: javaQuery},
+		}),
+	}
+	explicit := &queryalternatives.Querier{
+		Runner: fakeRunner(map[string]fakeCommand{
+			"--get-selections": {stdout: "editor                         manual   /bin/nano\n"},
+			"--query editor":   {stdout: editorQuery},
+		}),
+	}
+	ctx := queryalternatives.WithQuerier(context.Background(), fromContext)
+
+	result, err := queryalternatives.Query(ctx, "java")
+	require.NoError(t, err)
+	assert.Equal(t, "java", result.Name)
+
+	names, err := queryalternatives.Names(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"java"}, names)
+
+	// The Querier from the context is used when none is given explicitly.
+	groups, err := queryalternatives.Inventory(ctx, nil)
+	require.NoError(t, err)
+	require.Len(t, groups, 1)
+	assert.Equal(t, "java", groups[0].Name)
+
+	// An explicit Querier takes precedence.
+	groups, err = queryalternatives.Inventory(ctx, explicit)
+	require.NoError(t, err)
+	require.Len(t, groups, 1)
+	assert.Equal(t, "editor", groups[0].Name)
+}
+
+func Test_Version(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		stdout   string
+		expected string
+		wantErr  bool
+	}{
+		{
+			name: "debian",
+			stdout: "Debian update-alternatives version 1.21.22.\n\n" +
+				"This is free software; see the GNU General Public License version 2 or\n" +
+				"later for copying conditions. There is NO warranty.\n",
+			expected: "1.21.22",
+		},
+		{name: "without period", stdout: "alternatives version 1.24\n", expected: "1.24"},
+		{name: "debian revision", stdout: "Debian update-alternatives version 1.22.6ubuntu6.1.\n", expected: "1.22.6ubuntu6.1"},
+		{name: "garbage", stdout: "usage: update-alternatives [<option> ...] <command>\n", wantErr: true},
+		{name: "empty", stdout: "", wantErr: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			q := &queryalternatives.Querier{
+				Runner: fakeRunner(map[string]fakeCommand{
+					"--version": {stdout: test.stdout},
+				}),
+			}
+			result, err := queryalternatives.Version(context.Background(), q)
+			if test.wantErr {
+				var queryErr *queryalternatives.QueryError
+				assert.ErrorAs(t, err, &queryErr)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, test.expected, result)
+		})
+	}
+
+	ctx := queryalternatives.WithQuerier(context.Background(), &queryalternatives.Querier{
+		Runner: fakeRunner(map[string]fakeCommand{
+			"--version": {stderr: "update-alternatives: error: unknown option\n", exit: 2},
+		}),
+	})
+	_, err := queryalternatives.Version(ctx, nil)
+	var queryErr *queryalternatives.QueryError
+	require.ErrorAs(t, err, &queryErr)
+	assert.Equal(t, 2, queryErr.ExitStatus)
+}
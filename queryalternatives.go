@@ -4,22 +4,45 @@ import (
 	"bufio"
 	"bytes"
 	"context"
+	"errors"
 	"fmt"
 	"io"
-	"os/exec"
+	"iter"
+	"maps"
+	"regexp"
+	"slices"
 	"strconv"
 	"strings"
 )
 
+// Status is the mode of an alternatives group.
+type Status string
+
+const (
+	// StatusAuto means the system automatically selects the best alternative.
+	StatusAuto Status = "auto"
+	// StatusManual means the user has manually selected an alternative.
+	StatusManual Status = "manual"
+	// StatusUnknown means the status was not recognized. It is only set
+	// when Parser.Lenient is set, and the status as it appeared is kept in
+	// Alternatives.StatusRaw.
+	StatusUnknown Status = "unknown"
+)
+
 // Alternative represents an alternative for a specific command.
 type Alternative struct {
 	// Path is the path to the alternative.
 	Path string
 	// Priority is the priority of the alternative.
 	// Higher numbers indicate higher priority.
+	// It is in the range of a 32-bit integer, as in update-alternatives.
 	Priority int
+	// PriorityRaw is the priority exactly as it appeared in the input.
+	// It is only populated when Parser.KeepRaw is set.
+	PriorityRaw string `json:",omitempty"`
 	// Slaves is a map of slave links to their corresponding paths.
 	// Slaves are additional files that are linked to this alternative.
+	// The parsers set it to an empty map, not nil, if there are no slaves.
 	Slaves map[string]string
 }
 
@@ -33,12 +56,16 @@ type Alternatives struct {
 	// For example, "/usr/bin/java" for the Java alternatives.
 	Link string
 	// Slaves is a map of slave links to their corresponding paths.
+	// The parsers set it to an empty map, not nil, if there are no slaves.
 	Slaves map[string]string
 	// Status indicates the status of the alternatives group.
 	// It can be "auto" or "manual".
 	// "auto" means the system will automatically select the best alternative.
 	// "manual" means the user has manually selected an alternative.
-	Status string
+	Status Status
+	// StatusRaw is the status as it appeared in the input when it was not
+	// recognized and Status is StatusUnknown. It is empty otherwise.
+	StatusRaw string `json:",omitempty"`
 	// Best is the best alternative selected by the system.
 	// It is the path to the best alternative.
 	Best string
@@ -47,9 +74,75 @@ type Alternatives struct {
 	Value string
 	// Alternatives is alternatives for this group.
 	Alternatives []Alternative
+	// Unknown holds the values of unrecognized keys anywhere in the group,
+	// in the order they appeared. It is only populated when Parser.Lenient
+	// or Parser.KeepRaw is set; otherwise unknown keys are an error.
+	Unknown map[string][]string `json:",omitempty"`
+
+	// original holds the paths of Alternatives in the order they were
+	// parsed, or for a group which was not parsed, in the order before the
+	// first SortByPriority. See InOriginalOrder.
+	original []string
+}
+
+// ParseErrorCode identifies the kind of a ParseError.
+type ParseErrorCode int
+
+const (
+	// CodeUnknown is the code of a ParseError which has no specific kind.
+	CodeUnknown ParseErrorCode = iota
+	// CodeMalformedLine means a line is not in the `Key: value` form.
+	CodeMalformedLine
+	// CodeMalformedSlaves means a line of a Slaves block is not in the
+	// `link path` form.
+	CodeMalformedSlaves
+	// CodeUnexpectedKey means a key is not recognized at its position.
+	CodeUnexpectedKey
+	// CodeInvalidPriority means a priority is not an integer.
+	CodeInvalidPriority
+	// CodeNegativePriority means a priority is negative while
+	// Parser.RejectNegativePriority is set.
+	CodeNegativePriority
+	// CodeInvalidStatus means a status is not recognized.
+	CodeInvalidStatus
+	// CodeTooManySlaves means a Slaves block exceeds Parser.MaxSlavesPerBlock.
+	CodeTooManySlaves
+	// CodeUnexpectedEOF means the input ended prematurely.
+	CodeUnexpectedEOF
+	// CodeMissingField means a field which is always present in the output
+	// of update-alternatives, such as Name, is missing.
+	CodeMissingField
+)
+
+// Sentinel errors matched by errors.Is for a ParseError with the
+// corresponding code.
+var (
+	ErrMalformedLine    = errors.New("malformed line")
+	ErrMalformedSlaves  = errors.New("malformed slaves line")
+	ErrUnexpectedKey    = errors.New("unexpected key")
+	ErrInvalidPriority  = errors.New("invalid priority value")
+	ErrNegativePriority = errors.New("negative priority value")
+	ErrInvalidStatus    = errors.New("invalid status")
+	ErrTooManySlaves    = errors.New("too many slaves")
+	ErrUnexpectedEOF    = errors.New("unexpected end of file")
+	ErrMissingField     = errors.New("missing field")
+)
+
+var parseErrorSentinels = map[ParseErrorCode]error{
+	CodeMalformedLine:    ErrMalformedLine,
+	CodeMalformedSlaves:  ErrMalformedSlaves,
+	CodeUnexpectedKey:    ErrUnexpectedKey,
+	CodeInvalidPriority:  ErrInvalidPriority,
+	CodeNegativePriority: ErrNegativePriority,
+	CodeInvalidStatus:    ErrInvalidStatus,
+	CodeTooManySlaves:    ErrTooManySlaves,
+	CodeUnexpectedEOF:    ErrUnexpectedEOF,
+	CodeMissingField:     ErrMissingField,
 }
 
 type ParseError struct {
+	// Code identifies the kind of the error.
+	Code    ParseErrorCode
 	Message string
 	Line    int
 }
@@ -58,6 +151,31 @@ func (err *ParseError) Error() string {
 	return fmt.Sprintf("error parsing alternatives: %d: %s", err.Line, err.Message)
 }
 
+// Is reports whether target is the sentinel error for the code of err,
+// such as ErrMalformedLine.
+func (err *ParseError) Is(target error) bool {
+	sentinel, ok := parseErrorSentinels[err.Code]
+	return ok && sentinel == target
+}
+
+func (a *Alternatives) addUnknown(key, value string) {
+	if a.Unknown == nil {
+		a.Unknown = make(map[string][]string)
+	}
+	a.Unknown[key] = append(a.Unknown[key], value)
+}
+
+// parsePriority parses a priority. update-alternatives stores priorities
+// as a C int, so values outside of the 32-bit range are rejected on every
+// platform.
+func parsePriority(s string) (int, error) {
+	priority, err := strconv.ParseInt(s, 10, 32)
+	if err != nil {
+		return 0, err
+	}
+	return int(priority), nil
+}
+
 func newAlternative() *Alternative {
 	return &Alternative{
 		Slaves: make(map[string]string),
@@ -72,8 +190,66 @@ func newAlternatives() *Alternatives {
 }
 
 type Parser struct {
-	R      *bufio.Reader
+	R *bufio.Reader
+	// KeepRaw makes the parser retain the original text of values that are
+	// normalized while parsing, such as Alternative.PriorityRaw.
+	KeepRaw bool
+	// Lenient makes the parser accept output reformatted by third-party
+	// tools: `Mode:` is accepted as an alias of `Status:`, unknown keys
+	// are collected in Alternatives.Unknown instead of being an error, and
+	// an unrecognized status is parsed as StatusUnknown. A line without a
+	// colon may also use `=` as the separator, as in `Priority= 1081`; the
+	// colon takes precedence, so a line is only split at `=` if it contains
+	// no colon.
+	Lenient bool
+	// LenientStatus makes the parser accept variations of the status such as
+	// "Auto", "AUTO", "automatic" or "MANUAL". By default, only "auto" and
+	// "manual" are accepted.
+	LenientStatus bool
+	// SkipInfoLines makes the parser skip informational lines printed by
+	// update-alternatives, such as those printed with --verbose.
+	// Lines reporting an error abort parsing with a QueryError.
+	SkipInfoLines bool
+	// RejectNegativePriority makes the parser fail on negative priorities,
+	// which update-alternatives never emits but may appear in a corrupted
+	// database. By default, any integer is accepted.
+	RejectNegativePriority bool
+	// DetectCommandErrors makes the parser recognize messages of
+	// update-alternatives mixed into the input, such as when its standard
+	// output and standard error are merged. An `update-alternatives: error:`
+	// line anywhere in the input aborts parsing with a QueryError, and
+	// `update-alternatives: warning:` lines are skipped.
+	DetectCommandErrors bool
+	// MaxSlavesPerBlock limits the number of lines in a single Slaves block.
+	// Zero means no limit.
+	MaxSlavesPerBlock int
+	// LinePrefix, if non-nil, is stripped from the beginning of each line
+	// before it is interpreted, such as the timestamp and the identifier
+	// prepended by journald when the output is captured in logs. Lines which
+	// do not start with a match are interpreted as is. It must be set before
+	// the first call to a parsing method.
+	LinePrefix *regexp.Regexp
+	// GroupDelimiter, if non-empty, makes a line which starts with it, such
+	// as "---" for a marker like "--- java ---", end the current group in
+	// Decode and ParseAll. Groups are still split before `Name:` lines as
+	// well. Delimiter lines are otherwise ignored.
+	GroupDelimiter string
+
 	lineNo int
+	// stripping is set once R has been wrapped to strip LinePrefix.
+	stripping bool
+	// pending is a record which has been read but belongs to the next group.
+	pending *record
+	// header is the group-level fields parsed by ParseHeader.
+	header *Alternatives
+	// resync is set when a group failed to parse, so that the next call to
+	// Decode skips the rest of the group.
+	resync bool
+}
+
+type record struct {
+	key   string
+	value string
 }
 
 func NewParser(r io.Reader) *Parser {
@@ -89,7 +265,45 @@ func NewParser(r io.Reader) *Parser {
 	}
 }
 
+// stripPrefix wraps R so that LinePrefix is stripped from each line, if it
+// is set and R has not been wrapped yet.
+func (r *Parser) stripPrefix() {
+	if r.LinePrefix == nil || r.stripping {
+		return
+	}
+	r.R = bufio.NewReader(&prefixStripper{r: r.R, re: r.LinePrefix})
+	r.stripping = true
+}
+
+// prefixStripper reads lines from r, removing the match of re at the
+// beginning of each line.
+type prefixStripper struct {
+	r   *bufio.Reader
+	re  *regexp.Regexp
+	buf []byte
+	err error
+}
+
+func (s *prefixStripper) Read(p []byte) (int, error) {
+	for len(s.buf) == 0 {
+		if s.err != nil {
+			return 0, s.err
+		}
+		var line []byte
+		line, s.err = s.r.ReadBytes('\n')
+		if loc := s.re.FindIndex(line); loc != nil && loc[0] == 0 {
+			line = line[loc[1]:]
+		}
+		s.buf = line
+	}
+	n := copy(p, s.buf)
+	s.buf = s.buf[n:]
+	return n, nil
+}
+
 func (r *Parser) readKeyValue() (string, string, error) {
+	r.stripPrefix()
+
 	var line []byte
 	var err error
 	for {
@@ -111,9 +325,19 @@ func (r *Parser) readKeyValue() (string, string, error) {
 		}
 	}
 
+	if r.GroupDelimiter != "" && bytes.HasPrefix(line, []byte(r.GroupDelimiter)) {
+		return "", "", errGroupDelimiter
+	}
+
 	parts := bytes.SplitN(line, []byte(":"), 2)
+	if len(parts) != 2 && r.Lenient {
+		// `Key= value` is only accepted if there is no colon in the line.
+		parts = bytes.SplitN(line, []byte("="), 2)
+		parts[0] = bytes.TrimRight(parts[0], " ")
+	}
 	if len(parts) != 2 {
 		return "", "", &ParseError{
+			Code:    CodeMalformedLine,
 			Message: "malformed line",
 			Line:    r.lineNo,
 		}
@@ -122,6 +346,11 @@ func (r *Parser) readKeyValue() (string, string, error) {
 	key := string(parts[0])
 	var value strings.Builder
 	value.Write(bytes.TrimRight(bytes.TrimLeft(parts[1], " "), "\r\n"))
+	valueLines := 0
+	if value.Len() > 0 {
+		valueLines++
+	}
+	continued := false
 
 	for {
 		next, err := r.R.Peek(1)
@@ -147,6 +376,16 @@ func (r *Parser) readKeyValue() (string, string, error) {
 			}
 		}
 		r.lineNo++
+		continued = true
+
+		valueLines++
+		if key == "Slaves" && r.MaxSlavesPerBlock > 0 && valueLines > r.MaxSlavesPerBlock {
+			return "", "", &ParseError{
+				Code:    CodeTooManySlaves,
+				Message: fmt.Sprintf("too many slaves (limit %d)", r.MaxSlavesPerBlock),
+				Line:    r.lineNo,
+			}
+		}
 
 		if value.Len() > 0 {
 			value.WriteByte('\n')
@@ -154,9 +393,28 @@ func (r *Parser) readKeyValue() (string, string, error) {
 		value.Write(line)
 	}
 
+	if !continued {
+		// Trailing whitespace of a single-line value is not significant,
+		// while lines of a multi-line value, such as Slaves, are kept as is
+		// because a path may end with a space.
+		return key, strings.TrimRight(value.String(), " \t"), nil
+	}
 	return key, value.String(), nil
 }
 
+// errGroupDelimiter is returned by readKeyValue for a line starting with
+// Parser.GroupDelimiter.
+var errGroupDelimiter = errors.New("group delimiter")
+
+func (r *Parser) nextKeyValue() (string, string, error) {
+	if r.pending != nil {
+		rec := r.pending
+		r.pending = nil
+		return rec.key, rec.value, nil
+	}
+	return r.readKeyValue()
+}
+
 func (r *Parser) parseSlaves(input string) (map[string]string, error) {
 	slaves := make(map[string]string)
 	lines := strings.Split(input, "\n")
@@ -164,6 +422,7 @@ func (r *Parser) parseSlaves(input string) (map[string]string, error) {
 		parts := strings.SplitN(line, " ", 2)
 		if len(parts) != 2 {
 			return nil, &ParseError{
+				Code:    CodeMalformedSlaves,
 				Message: "malformed slaves line",
 				Line:    r.lineNo,
 			}
@@ -173,12 +432,126 @@ func (r *Parser) parseSlaves(input string) (map[string]string, error) {
 	return slaves, nil
 }
 
+func (r *Parser) parseStatus(input string) (Status, error) {
+	switch Status(input) {
+	case StatusAuto, StatusManual:
+		return Status(input), nil
+	}
+
+	if r.LenientStatus {
+		switch strings.ToLower(input) {
+		case "auto", "automatic":
+			return StatusAuto, nil
+		case "manual":
+			return StatusManual, nil
+		}
+	}
+
+	return "", &ParseError{
+		Code:    CodeInvalidStatus,
+		Message: fmt.Sprintf("invalid status: %q", input),
+		Line:    r.lineNo,
+	}
+}
+
 func (r *Parser) Parse() (*Alternatives, error) {
-	result := newAlternatives()
-	var currentAlt *Alternative
+	return r.parse(false, "", "")
+}
+
+// ParseHeader parses the group-level fields (Name, Link, Slaves, Status,
+// Best and Value) and stops right before the first `Alternative:` line,
+// leaving the remaining input unread. As with Decode, a `Name:` line of the
+// next group also ends the header, so a group without alternatives is not
+// merged into the following one, and io.EOF is returned if there is no more
+// input.
+// A subsequent call to Parse or Decode continues from there and returns
+// the whole group, including the header parsed by ParseHeader.
+func (r *Parser) ParseHeader() (*Alternatives, error) {
+	result, err := r.parse(true, "Alternative", "")
+	if err != nil {
+		return nil, err
+	}
+	// The header is kept for Parse and Decode, which fill in the rest of
+	// the group, so it must not share anything with the returned one.
+	header := *result
+	header.Slaves = maps.Clone(result.Slaves)
+	header.Alternatives = slices.Clone(result.Alternatives)
+	if result.Unknown != nil {
+		header.Unknown = make(map[string][]string, len(result.Unknown))
+		for k, v := range result.Unknown {
+			header.Unknown[k] = slices.Clone(v)
+		}
+	}
+	r.header = &header
+	return result, nil
+}
+
+// atKey reports whether the next non-empty line starts with key.
+// Empty lines before it are consumed.
+func (r *Parser) atKey(key string) (bool, error) {
+	if r.pending != nil {
+		return r.pending.key == key, nil
+	}
+	r.stripPrefix()
+
+	for {
+		b, err := r.R.Peek(1)
+		if err != nil {
+			if err == io.EOF {
+				return false, nil
+			}
+			return false, err
+		}
+		if b[0] != '\r' && b[0] != '\n' {
+			break
+		}
+		r.R.ReadByte()
+		if b[0] == '\n' {
+			r.lineNo++
+		}
+	}
+
+	prefix := key + ":"
+	b, err := r.R.Peek(len(prefix))
+	if err != nil && err != io.EOF {
+		return false, err
+	}
+	return string(b) == prefix, nil
+}
+
+// parse parses a single group. If multi is true, a `Name:` line after
+// the beginning of the group is left for the next call, and io.EOF is
+// returned if there is no more input. If stopBefore is non-empty, parsing
+// stops before the first line with that key. If stopAfter is non-empty,
+// parsing stops after the first group-level line with that key.
+func (r *Parser) parse(multi bool, stopBefore, stopAfter string) (*Alternatives, error) {
+	b := &builder{r: r, result: newAlternatives()}
+	empty := true
+	if r.header != nil {
+		// Continue the group started by ParseHeader.
+		b.result = r.header
+		r.header = nil
+		empty = false
+	}
 
 	for {
-		k, v, err := r.readKeyValue()
+		if stopBefore != "" {
+			at, err := r.atKey(stopBefore)
+			if err != nil {
+				return nil, err
+			}
+			if at {
+				break
+			}
+		}
+
+		k, v, err := r.nextKeyValue()
+		if err == errGroupDelimiter {
+			if multi && !empty {
+				break
+			}
+			continue
+		}
 		if err != nil {
 			if err == io.EOF {
 				break
@@ -186,111 +559,296 @@ func (r *Parser) Parse() (*Alternatives, error) {
 			return nil, err
 		}
 
-		if currentAlt == nil {
-			switch k {
-			case "Name":
-				result.Name = v
-			case "Link":
-				result.Link = v
-			case "Slaves":
-				var err error
-				result.Slaves, err = r.parseSlaves(v)
-				if err != nil {
-					return nil, err
-				}
-			case "Status":
-				result.Status = v
-			case "Best":
-				result.Best = v
-			case "Value":
-				result.Value = v
-			case "Alternative":
-				currentAlt = newAlternative()
-				currentAlt.Path = v
-			default:
-				return nil, &ParseError{
-					Message: fmt.Sprintf("unexpected key: %s", k),
-					Line:    r.lineNo,
-				}
-			}
-		} else {
-			switch k {
-			case "Priority":
-				priority, err := strconv.Atoi(v)
-				if err != nil {
-					return nil, &ParseError{
-						Message: "invalid priority value",
-						Line:    r.lineNo,
-					}
-				}
-				currentAlt.Priority = priority
-			case "Slaves":
-				var err error
-				currentAlt.Slaves, err = r.parseSlaves(v)
-				if err != nil {
-					return nil, err
-				}
-			case "Alternative":
-				// Save the previous alternative before starting a new one
-				result.Alternatives = append(result.Alternatives, *currentAlt)
-
-				currentAlt = newAlternative()
-				currentAlt.Path = v
-			default:
-				return nil, &ParseError{
-					Message: fmt.Sprintf("unexpected key: %s", k),
-					Line:    r.lineNo,
-				}
+		if k == "update-alternatives" && (r.SkipInfoLines || r.DetectCommandErrors) {
+			switch {
+			case strings.HasPrefix(v, "error:"):
+				return nil, classifyQueryError(&QueryError{
+					Message: k + ": " + v,
+				})
+			case r.SkipInfoLines, strings.HasPrefix(v, "warning:"):
+				continue
 			}
 		}
+
+		if multi && k == "Name" && !empty {
+			// This is the beginning of the next group.
+			r.pending = &record{key: k, value: v}
+			break
+		}
+		empty = false
+
+		groupLevel := b.currentAlt == nil
+		if err := b.add(k, v); err != nil {
+			return nil, err
+		}
+		if groupLevel && k == stopAfter {
+			break
+		}
 	}
 
-	if currentAlt != nil {
+	if multi && empty {
+		return nil, io.EOF
+	}
+
+	return b.finish(), nil
+}
+
+// builder interprets the records of a single group.
+type builder struct {
+	r          *Parser
+	result     *Alternatives
+	currentAlt *Alternative
+}
+
+// add interprets a single record using the interpreter registered for the
+// key at the current position.
+func (b *builder) add(k, v string) error {
+	if b.currentAlt == nil && b.r.Lenient && k == "Mode" {
+		k = "Status"
+	}
+
+	f, ok := lookupKey(k, b.currentAlt != nil)
+	if !ok {
+		if b.r.Lenient || b.r.KeepRaw {
+			b.result.addUnknown(k, v)
+			return nil
+		}
+		return &ParseError{
+			Code:    CodeUnexpectedKey,
+			Message: fmt.Sprintf("unexpected key: %s", k),
+			Line:    b.r.lineNo,
+		}
+	}
+	return f(b, v)
+}
+
+// finish returns the group built from the records added so far.
+func (b *builder) finish() *Alternatives {
+	if b.currentAlt != nil {
 		// Save the last alternative
-		result.Alternatives = append(result.Alternatives, *currentAlt)
+		b.result.Alternatives = append(b.result.Alternatives, *b.currentAlt)
+		b.currentAlt = nil
+	}
+	b.result.recordOriginalOrder()
+	return b.result
+}
+
+// Decode reads the next group from input which contains the output of
+// several `update-alternatives --query` invocations concatenated together.
+// Each group starts with a `Name:` line.
+// Decode returns io.EOF when there are no more groups.
+// If a group fails to parse, the next call to Decode skips the rest of it
+// and continues with the following group.
+func (r *Parser) Decode() (*Alternatives, error) {
+	if r.resync {
+		if err := r.skipGroup(); err != nil {
+			return nil, err
+		}
+		r.resync = false
+	}
+
+	result, err := r.parse(true, "", "")
+	var parseErr *ParseError
+	if errors.As(err, &parseErr) {
+		r.resync = true
 	}
+	return result, err
+}
 
+// ParseUntil is like Decode, but stops reading as soon as the group-level
+// key stopKey, such as "Status", has been read, which avoids reading the
+// alternatives when only the beginning of each group is needed.
+// The result only contains the fields which appear up to stopKey, and the
+// rest of the group is left unread; the next call to ParseUntil or Decode
+// skips it and continues with the following group. If stopKey does not
+// appear, the whole group is read.
+func (r *Parser) ParseUntil(stopKey string) (*Alternatives, error) {
+	if r.resync {
+		if err := r.skipGroup(); err != nil {
+			return nil, err
+		}
+		r.resync = false
+	}
+
+	result, err := r.parse(true, "", stopKey)
+	if err != io.EOF {
+		// Skip the rest of the group, if any, on the next call.
+		r.resync = true
+	}
+	return result, err
+}
+
+// skipGroup discards records until the beginning of the next group.
+func (r *Parser) skipGroup() error {
+	for {
+		k, v, err := r.nextKeyValue()
+		if err == errGroupDelimiter {
+			return nil
+		}
+		if err != nil {
+			var parseErr *ParseError
+			if errors.As(err, &parseErr) {
+				continue
+			}
+			return err
+		}
+		if k == "Name" {
+			r.pending = &record{key: k, value: v}
+			return nil
+		}
+	}
+}
+
+// ParseAll parses all groups in the input. See Decode for the input format.
+// It fails if any of the groups fails to parse.
+func (r *Parser) ParseAll() ([]*Alternatives, error) {
+	result := make([]*Alternatives, 0)
+	for {
+		alts, err := r.Decode()
+		if err != nil {
+			if err == io.EOF {
+				break
+			}
+			return nil, err
+		}
+		result = append(result, alts)
+	}
 	return result, nil
 }
 
+// GroupResult is the result of parsing a single group.
+// Exactly one of Group and Err is non-nil.
+type GroupResult struct {
+	Group *Alternatives
+	Err   error
+}
+
+// ParseAllResults is like ParseAll, but a group that fails to parse does not
+// abort parsing. Instead, the error is recorded in the corresponding
+// GroupResult and parsing continues with the next group.
+// Parsing stops at the first error which is not a ParseError, such as
+// a read error, which is recorded as the last result.
+func (r *Parser) ParseAllResults() []GroupResult {
+	result := make([]GroupResult, 0)
+	for {
+		alts, err := r.Decode()
+		if err == io.EOF {
+			break
+		}
+		result = append(result, GroupResult{Group: alts, Err: err})
+
+		var parseErr *ParseError
+		if err != nil && !errors.As(err, &parseErr) {
+			break
+		}
+	}
+	return result
+}
+
+// ParseReaders parses each of readers, which may contain one or more groups
+// (see ParseAll), and returns all groups in order.
+// Readers which fail to parse contribute no groups, and their errors,
+// prefixed with the 0-based index of the reader, are joined into the
+// returned error, so the result is usable even if the error is non-nil.
+func ParseReaders(readers ...io.Reader) ([]*Alternatives, error) {
+	result := make([]*Alternatives, 0, len(readers))
+	var errs []error
+	for i, r := range readers {
+		groups, err := NewParser(r).ParseAll()
+		if err != nil {
+			errs = append(errs, fmt.Errorf("reader %d: %w", i, err))
+			continue
+		}
+		result = append(result, groups...)
+	}
+	return result, errors.Join(errs...)
+}
+
 // ParseString parses a string and returns an Alternatives object.
 func ParseString(input string) (*Alternatives, error) {
 	return NewParser(strings.NewReader(input)).Parse()
 }
 
+// ParseRecords interprets a sequence of key/value records of a single group,
+// such as the output of `update-alternatives --query` which has already been
+// split into records by other means.
+// The value of a record spanning multiple lines, such as Slaves, must have
+// its lines joined with "\n" and the leading spaces removed.
+// The Line of a returned ParseError is the 1-based index of the record.
+func ParseRecords(records iter.Seq2[string, string]) (*Alternatives, error) {
+	r := &Parser{}
+	b := &builder{r: r, result: newAlternatives()}
+	for k, v := range records {
+		r.lineNo++
+		if err := b.add(k, v); err != nil {
+			return nil, err
+		}
+	}
+	return b.finish(), nil
+}
+
 type QueryError struct {
 	ExitStatus int
 	Message    string
+	// Args is the command line of the failed command, if any.
+	Args []string
 }
 
 func (e *QueryError) Error() string {
 	return "error querying alternatives: " + e.Message
 }
 
-// Query executes the `update-alternatives --query` command and returns the parsed result.
-func Query(ctx context.Context, query string) (*Alternatives, error) {
-	cmd := exec.CommandContext(ctx, "update-alternatives", "--query", query)
-	stdout, err := cmd.StdoutPipe()
-	if err != nil {
-		return nil, err
-	}
-	defer stdout.Close()
+var (
+	// ErrNotFound is matched by errors.Is when the alternatives group does not exist.
+	ErrNotFound = errors.New("no alternatives found")
+	// ErrPermission is matched by errors.Is when update-alternatives lacks
+	// the privileges to perform the operation.
+	ErrPermission = errors.New("permission denied")
+)
 
-	if err := cmd.Start(); err != nil {
-		return nil, err
-	}
+// NotFoundError is returned when the requested alternatives group does not exist.
+type NotFoundError struct {
+	QueryError
+}
 
-	result, err := NewParser(stdout).Parse()
+func (e *NotFoundError) Is(target error) bool {
+	return target == ErrNotFound
+}
 
-	if err := cmd.Wait(); err != nil {
-		if exitErr, ok := err.(*exec.ExitError); ok {
-			return nil, &QueryError{
-				ExitStatus: exitErr.ExitCode(),
-				Message:    string(exitErr.Stderr),
-			}
-		}
-		return nil, err
+func (e *NotFoundError) Unwrap() error {
+	return &e.QueryError
+}
+
+// PermissionError is returned when update-alternatives fails because it is
+// not run with sufficient privileges (typically, not as root).
+type PermissionError struct {
+	QueryError
+}
+
+func (e *PermissionError) Is(target error) bool {
+	return target == ErrPermission
+}
+
+func (e *PermissionError) Unwrap() error {
+	return &e.QueryError
+}
+
+// classifyQueryError returns a more specific error for err if its message
+// indicates a known failure, or err itself otherwise.
+func classifyQueryError(err *QueryError) error {
+	message := strings.ToLower(err.Message)
+	switch {
+	case strings.Contains(message, "no alternatives for"):
+		return &NotFoundError{QueryError: *err}
+	case strings.Contains(message, "permission denied"),
+		strings.Contains(message, "operation not permitted"):
+		return &PermissionError{QueryError: *err}
 	}
+	return err
+}
 
-	return result, err
+// Query executes the `update-alternatives --query` command and returns the parsed result.
+// It uses the Querier carried by ctx (see WithQuerier).
+func Query(ctx context.Context, query string) (*Alternatives, error) {
+	return querierFrom(ctx).Query(ctx, query)
 }
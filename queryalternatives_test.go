@@ -2,6 +2,13 @@ package queryalternatives_test
 
 import (
 	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"iter"
+	"math"
+	"regexp"
 	"strings"
 	"testing"
 
@@ -109,6 +116,38 @@ Value: /usr/lib/jvm/java-21-openjdk-amd64/bin/java`,
 				Alternatives: []queryalternatives.Alternative{},
 			},
 		},
+		{
+			name: "valid input ending with slaves block without new line at the end",
+			input: `Name: java
+Link: /usr/bin/java
+Status: auto
+Best: /usr/lib/jvm/java-21-openjdk-amd64/bin/java
+Value: /usr/lib/jvm/java-21-openjdk-amd64/bin/java
+
+Alternative: /usr/lib/jvm/java-21-openjdk-amd64/bin/java
+Priority: 2111
+Slaves:
+ java.1.gz /usr/lib/jvm/java-21-openjdk-amd64/man/man1/java.1.gz
+ java.ja.1.gz /usr/lib/jvm/java-21-openjdk-amd64/man/ja/man1/java.1.gz`,
+			expected: &queryalternatives.Alternatives{
+				Name:   "java",
+				Link:   "/usr/bin/java",
+				Slaves: map[string]string{},
+				Status: "auto",
+				Best:   "/usr/lib/jvm/java-21-openjdk-amd64/bin/java",
+				Value:  "/usr/lib/jvm/java-21-openjdk-amd64/bin/java",
+				Alternatives: []queryalternatives.Alternative{
+					{
+						Path:     "/usr/lib/jvm/java-21-openjdk-amd64/bin/java",
+						Priority: 2111,
+						Slaves: map[string]string{
+							"java.1.gz":    "/usr/lib/jvm/java-21-openjdk-amd64/man/man1/java.1.gz",
+							"java.ja.1.gz": "/usr/lib/jvm/java-21-openjdk-amd64/man/ja/man1/java.1.gz",
+						},
+					},
+				},
+			},
+		},
 	}
 
 	for _, test := range tests {
@@ -118,7 +157,7 @@ Value: /usr/lib/jvm/java-21-openjdk-amd64/bin/java`,
 			reader := queryalternatives.NewParser(bufio.NewReader(strings.NewReader(test.input)))
 			result, err := reader.Parse()
 			assert.NoError(t, err)
-			assert.Equal(t, test.expected, result)
+			assertEqualGroup(t, test.expected, result)
 		})
 	}
 }
@@ -132,3 +171,1183 @@ func Test_ParseString_Error(t *testing.T) {
 	assert.Error(t, err, "expected an error")
 	assert.Nil(t, result)
 }
+
+func Test_Parser_KeepRaw(t *testing.T) {
+	t.Parallel()
+
+	input := `Name: java
+Link: /usr/bin/java
+Status: auto
+Best: /usr/lib/jvm/java-21-openjdk-amd64/bin/java
+Value: /usr/lib/jvm/java-21-openjdk-amd64/bin/java
+
+Alternative: /usr/lib/jvm/java-21-openjdk-amd64/bin/java
+Priority: 0100
+`
+
+	tests := []struct {
+		name        string
+		keepRaw     bool
+		expectedRaw string
+	}{
+		{
+			name:        "enabled",
+			keepRaw:     true,
+			expectedRaw: "0100",
+		},
+		{
+			name:        "disabled",
+			keepRaw:     false,
+			expectedRaw: "",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			parser := queryalternatives.NewParser(strings.NewReader(input))
+			parser.KeepRaw = test.keepRaw
+			result, err := parser.Parse()
+			assert.NoError(t, err)
+			if assert.Len(t, result.Alternatives, 1) {
+				assert.Equal(t, 100, result.Alternatives[0].Priority)
+				assert.Equal(t, test.expectedRaw, result.Alternatives[0].PriorityRaw)
+			}
+		})
+	}
+}
+
+func Test_Parser_LenientStatus(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		value    string
+		lenient  bool
+		expected queryalternatives.Status
+		wantErr  bool
+	}{
+		{value: "auto", lenient: false, expected: queryalternatives.StatusAuto},
+		{value: "manual", lenient: false, expected: queryalternatives.StatusManual},
+		{value: "Auto", lenient: false, wantErr: true},
+		{value: "automatic", lenient: false, wantErr: true},
+		{value: "Auto", lenient: true, expected: queryalternatives.StatusAuto},
+		{value: "AUTO", lenient: true, expected: queryalternatives.StatusAuto},
+		{value: "automatic", lenient: true, expected: queryalternatives.StatusAuto},
+		{value: "Manual", lenient: true, expected: queryalternatives.StatusManual},
+		{value: "MANUAL", lenient: true, expected: queryalternatives.StatusManual},
+		{value: "broken", lenient: true, wantErr: true},
+	}
+
+	for _, test := range tests {
+		t.Run(fmt.Sprintf("%s/lenient=%t", test.value, test.lenient), func(t *testing.T) {
+			t.Parallel()
+
+			parser := queryalternatives.NewParser(strings.NewReader("Name: java\nStatus: " + test.value + "\n"))
+			parser.LenientStatus = test.lenient
+			result, err := parser.Parse()
+			if test.wantErr {
+				var parseErr *queryalternatives.ParseError
+				if assert.ErrorAs(t, err, &parseErr) {
+					assert.Equal(t, 2, parseErr.Line)
+					assert.Contains(t, parseErr.Message, `"`+test.value+`"`)
+				}
+				assert.Nil(t, result)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, test.expected, result.Status)
+		})
+	}
+}
+
+func Test_Parser_Lenient_UnknownStatus(t *testing.T) {
+	t.Parallel()
+
+	input := "Name: java\nLink: /usr/bin/java\nStatus: broken\nValue: none\n"
+
+	parser := queryalternatives.NewParser(strings.NewReader(input))
+	parser.Lenient = true
+	result, err := parser.Parse()
+	assert.NoError(t, err)
+	assert.Equal(t, queryalternatives.StatusUnknown, result.Status)
+	assert.Equal(t, "broken", result.StatusRaw)
+	assert.Equal(t, "none", result.Value)
+
+	var b strings.Builder
+	_, err = result.WriteTo(&b)
+	assert.NoError(t, err)
+	assert.Equal(t, input, b.String())
+
+	parser = queryalternatives.NewParser(strings.NewReader("Name: java\nStatus: auto\n"))
+	parser.Lenient = true
+	result, err = parser.Parse()
+	assert.NoError(t, err)
+	assert.Equal(t, queryalternatives.StatusAuto, result.Status)
+	assert.Empty(t, result.StatusRaw)
+
+	result, err = queryalternatives.ParseString(input)
+	assert.Nil(t, result)
+	assert.ErrorIs(t, err, queryalternatives.ErrInvalidStatus)
+}
+
+func Test_Parser_GroupDelimiter(t *testing.T) {
+	t.Parallel()
+
+	input := `--- editor ---
+Name: editor
+Link: /usr/bin/editor
+Status: auto
+Value: /usr/bin/vim.basic
+Alternative: /usr/bin/vim.basic
+Priority: 50
+--- pager ---
+Link: /usr/bin/pager
+Status: auto
+Value: none
+--- java ---
+Name: java
+Link: /usr/bin/java
+Status: manual
+Value: /usr/lib/jvm/java-8-openjdk-amd64/jre/bin/java
+Alternative: /usr/lib/jvm/java-8-openjdk-amd64/jre/bin/java
+Priority: 1081
+---
+`
+
+	parser := queryalternatives.NewParser(strings.NewReader(input))
+	parser.GroupDelimiter = "---"
+	result, err := parser.ParseAll()
+	assert.NoError(t, err)
+	if assert.Len(t, result, 3) {
+		assert.Equal(t, "editor", result[0].Name)
+		assert.Len(t, result[0].Alternatives, 1)
+		// The group without a Name line is split by the delimiter alone.
+		assert.Equal(t, "", result[1].Name)
+		assert.Equal(t, "/usr/bin/pager", result[1].Link)
+		assert.Empty(t, result[1].Alternatives)
+		assert.Equal(t, "java", result[2].Name)
+		assert.Len(t, result[2].Alternatives, 1)
+	}
+
+	// After a broken group, Decode resumes at the next delimiter.
+	parser = queryalternatives.NewParser(strings.NewReader(strings.Replace(input, "Priority: 50", "Priority: fifty", 1)))
+	parser.GroupDelimiter = "---"
+	_, err = parser.Decode()
+	assert.ErrorIs(t, err, queryalternatives.ErrInvalidPriority)
+	pager, err := parser.Decode()
+	assert.NoError(t, err)
+	assert.Equal(t, "/usr/bin/pager", pager.Link)
+
+	// Without GroupDelimiter, the marker is a malformed line.
+	_, err = queryalternatives.NewParser(strings.NewReader(input)).ParseAll()
+	assert.ErrorIs(t, err, queryalternatives.ErrMalformedLine)
+}
+
+func Test_Parser_ParseAll(t *testing.T) {
+	t.Parallel()
+
+	input := `Name: editor
+Link: /usr/bin/editor
+Status: auto
+Best: /usr/bin/vim.basic
+Value: /usr/bin/vim.basic
+
+Alternative: /usr/bin/vim.basic
+Priority: 50
+
+Name: java
+Link: /usr/bin/java
+Status: manual
+Best: /usr/lib/jvm/java-21-openjdk-amd64/bin/java
+Value: /usr/lib/jvm/java-8-openjdk-amd64/jre/bin/java
+
+Alternative: /usr/lib/jvm/java-21-openjdk-amd64/bin/java
+Priority: 2111
+
+Alternative: /usr/lib/jvm/java-8-openjdk-amd64/jre/bin/java
+Priority: 1081
+`
+
+	result, err := queryalternatives.NewParser(strings.NewReader(input)).ParseAll()
+	assert.NoError(t, err)
+	if assert.Len(t, result, 2) {
+		assert.Equal(t, "editor", result[0].Name)
+		assert.Len(t, result[0].Alternatives, 1)
+		assert.Equal(t, "java", result[1].Name)
+		assert.Equal(t, queryalternatives.StatusManual, result[1].Status)
+		assert.Len(t, result[1].Alternatives, 2)
+	}
+
+	result, err = queryalternatives.NewParser(strings.NewReader("")).ParseAll()
+	assert.NoError(t, err)
+	assert.Empty(t, result)
+}
+
+func Test_Parser_ParseAllResults(t *testing.T) {
+	t.Parallel()
+
+	input := `Name: editor
+Link: /usr/bin/editor
+Status: auto
+Best: /usr/bin/vim.basic
+Value: /usr/bin/vim.basic
+
+Alternative: /usr/bin/vim.basic
+Priority: 50
+
+Name: java
+Link: /usr/bin/java
+Status: auto
+Best: /usr/lib/jvm/java-21-openjdk-amd64/bin/java
+Value: /usr/lib/jvm/java-21-openjdk-amd64/bin/java
+
+Alternative: /usr/lib/jvm/java-21-openjdk-amd64/bin/java
+Priority: high
+Slaves:
+ java.1.gz /usr/lib/jvm/java-21-openjdk-amd64/man/man1/java.1.gz
+this line is broken
+
+Name: pager
+Link: /usr/bin/pager
+Status: auto
+Best: /usr/bin/less
+Value: /usr/bin/less
+
+Alternative: /usr/bin/less
+Priority: 77
+`
+
+	results := queryalternatives.NewParser(strings.NewReader(input)).ParseAllResults()
+	if !assert.Len(t, results, 3) {
+		return
+	}
+
+	assert.NoError(t, results[0].Err)
+	assert.Equal(t, "editor", results[0].Group.Name)
+
+	assert.Nil(t, results[1].Group)
+	var parseErr *queryalternatives.ParseError
+	if assert.ErrorAs(t, results[1].Err, &parseErr) {
+		assert.Equal(t, 17, parseErr.Line)
+	}
+
+	assert.NoError(t, results[2].Err)
+	assert.Equal(t, "pager", results[2].Group.Name)
+	assert.Len(t, results[2].Group.Alternatives, 1)
+
+	_, err := queryalternatives.NewParser(strings.NewReader(input)).ParseAll()
+	assert.ErrorAs(t, err, &parseErr)
+}
+
+func Test_Parser_SkipInfoLines(t *testing.T) {
+	t.Parallel()
+
+	input := `update-alternatives: using /var/lib/dpkg/alternatives as admin directory
+Name: java
+Link: /usr/bin/java
+Status: auto
+Best: /usr/lib/jvm/java-21-openjdk-amd64/bin/java
+Value: /usr/lib/jvm/java-21-openjdk-amd64/bin/java
+
+Alternative: /usr/lib/jvm/java-21-openjdk-amd64/bin/java
+Priority: 2111
+update-alternatives: warning: forcing reinstallation of alternative /usr/bin/java
+`
+
+	parser := queryalternatives.NewParser(strings.NewReader(input))
+	parser.SkipInfoLines = true
+	result, err := parser.Parse()
+	assert.NoError(t, err)
+	assert.Equal(t, "java", result.Name)
+	assert.Len(t, result.Alternatives, 1)
+
+	_, err = queryalternatives.ParseString(input)
+	var parseErr *queryalternatives.ParseError
+	assert.ErrorAs(t, err, &parseErr)
+
+	parser = queryalternatives.NewParser(strings.NewReader(`update-alternatives: using /var/lib/dpkg/alternatives as admin directory
+update-alternatives: error: no alternatives for java
+`))
+	parser.SkipInfoLines = true
+	result, err = parser.Parse()
+	assert.Nil(t, result)
+	var queryErr *queryalternatives.QueryError
+	if assert.ErrorAs(t, err, &queryErr) {
+		assert.Equal(t, "update-alternatives: error: no alternatives for java", queryErr.Message)
+	}
+	assert.ErrorIs(t, err, queryalternatives.ErrNotFound)
+}
+
+func Test_Parser_DetectCommandErrors(t *testing.T) {
+	t.Parallel()
+
+	input := `Name: java
+Link: /usr/bin/java
+update-alternatives: warning: alternative /usr/lib/jvm/java-8-openjdk-amd64/jre/bin/java (part of link group java) doesn't exist; removing from list of alternatives
+Status: auto
+Best: /usr/lib/jvm/java-21-openjdk-amd64/bin/java
+Value: /usr/lib/jvm/java-21-openjdk-amd64/bin/java
+
+Alternative: /usr/lib/jvm/java-21-openjdk-amd64/bin/java
+update-alternatives: warning: /etc/alternatives/java is dangling; it will be updated with best choice
+Priority: 2111
+`
+
+	parser := queryalternatives.NewParser(strings.NewReader(input))
+	parser.DetectCommandErrors = true
+	result, err := parser.Parse()
+	assert.NoError(t, err)
+	assert.Equal(t, "java", result.Name)
+	if assert.Len(t, result.Alternatives, 1) {
+		assert.Equal(t, 2111, result.Alternatives[0].Priority)
+	}
+
+	parser = queryalternatives.NewParser(strings.NewReader(input + `update-alternatives: error: unable to make /etc/alternatives/java.dpkg-tmp a symlink: Permission denied
+`))
+	parser.DetectCommandErrors = true
+	result, err = parser.Parse()
+	assert.Nil(t, result)
+	var queryErr *queryalternatives.QueryError
+	if assert.ErrorAs(t, err, &queryErr) {
+		assert.Equal(t, "update-alternatives: error: unable to make /etc/alternatives/java.dpkg-tmp a symlink: Permission denied", queryErr.Message)
+	}
+	assert.ErrorIs(t, err, queryalternatives.ErrPermission)
+
+	// Other messages are not recognized unless SkipInfoLines is set.
+	parser = queryalternatives.NewParser(strings.NewReader("update-alternatives: using /var/lib/dpkg/alternatives as admin directory\n" + input))
+	parser.DetectCommandErrors = true
+	_, err = parser.Parse()
+	assert.ErrorIs(t, err, queryalternatives.ErrUnexpectedKey)
+}
+
+func Test_ParseString_PriorityRange(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		priority string
+		expected int
+		err      bool
+	}{
+		{name: "max", priority: "2147483647", expected: math.MaxInt32},
+		{name: "min", priority: "-2147483648", expected: math.MinInt32},
+		{name: "above max", priority: "2147483648", err: true},
+		{name: "below min", priority: "-2147483649", err: true},
+		{name: "beyond int64", priority: "99999999999999999999", err: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			result, err := queryalternatives.ParseString("Name: java\nAlternative: /usr/bin/java\nPriority: " + test.priority + "\n")
+			if test.err {
+				assert.ErrorIs(t, err, queryalternatives.ErrInvalidPriority)
+				return
+			}
+			if assert.NoError(t, err) && assert.Len(t, result.Alternatives, 1) {
+				assert.Equal(t, test.expected, result.Alternatives[0].Priority)
+			}
+		})
+	}
+}
+
+func Test_Parser_RejectNegativePriority(t *testing.T) {
+	t.Parallel()
+
+	input := `Name: java
+Link: /usr/bin/java
+Status: auto
+Best: /usr/lib/jvm/java-21-openjdk-amd64/bin/java
+Value: /usr/lib/jvm/java-21-openjdk-amd64/bin/java
+
+Alternative: /usr/lib/jvm/java-21-openjdk-amd64/bin/java
+Priority: -5
+`
+
+	parser := queryalternatives.NewParser(strings.NewReader(input))
+	result, err := parser.Parse()
+	assert.NoError(t, err)
+	if assert.Len(t, result.Alternatives, 1) {
+		assert.Equal(t, -5, result.Alternatives[0].Priority)
+	}
+
+	parser = queryalternatives.NewParser(strings.NewReader(input))
+	parser.RejectNegativePriority = true
+	result, err = parser.Parse()
+	assert.Nil(t, result)
+	var parseErr *queryalternatives.ParseError
+	if assert.ErrorAs(t, err, &parseErr) {
+		assert.Equal(t, 8, parseErr.Line)
+	}
+}
+
+func Test_Parser_MaxSlavesPerBlock(t *testing.T) {
+	t.Parallel()
+
+	input := `Name: java
+Link: /usr/bin/java
+Slaves:
+ java.1.gz /usr/share/man/man1/java.1.gz
+ java.ja.1.gz /usr/share/man/ja/man1/java.1.gz
+ jexec /usr/bin/jexec
+Status: auto
+`
+
+	tests := []struct {
+		name         string
+		max          int
+		expectedLine int
+	}{
+		{name: "unlimited", max: 0},
+		{name: "at limit", max: 3},
+		{name: "over limit", max: 2, expectedLine: 6},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			parser := queryalternatives.NewParser(strings.NewReader(input))
+			parser.MaxSlavesPerBlock = test.max
+			result, err := parser.Parse()
+			if test.expectedLine == 0 {
+				assert.NoError(t, err)
+				assert.Len(t, result.Slaves, 3)
+				return
+			}
+			assert.Nil(t, result)
+			var parseErr *queryalternatives.ParseError
+			if assert.ErrorAs(t, err, &parseErr) {
+				assert.Equal(t, test.expectedLine, parseErr.Line)
+			}
+		})
+	}
+}
+
+func Test_Alternatives_MarshalJSON_Deterministic(t *testing.T) {
+	t.Parallel()
+
+	a := &queryalternatives.Alternatives{
+		Name: "java",
+		Link: "/usr/bin/java",
+		Slaves: map[string]string{
+			"jexec":        "/usr/bin/jexec",
+			"java.ja.1.gz": "/usr/share/man/ja/man1/java.1.gz",
+			"java.1.gz":    "/usr/share/man/man1/java.1.gz",
+		},
+		Status: queryalternatives.StatusAuto,
+		Best:   "/usr/lib/jvm/java-21-openjdk-amd64/bin/java",
+		Value:  "/usr/lib/jvm/java-21-openjdk-amd64/bin/java",
+		Alternatives: []queryalternatives.Alternative{
+			{
+				Path:     "/usr/lib/jvm/java-21-openjdk-amd64/bin/java",
+				Priority: 2111,
+				Slaves: map[string]string{
+					"jexec":     "/usr/lib/jvm/java-21-openjdk-amd64/lib/jexec",
+					"java.1.gz": "/usr/lib/jvm/java-21-openjdk-amd64/man/man1/java.1.gz",
+				},
+			},
+		},
+	}
+
+	expected := `{"Name":"java","Link":"/usr/bin/java",` +
+		`"Slaves":{"java.1.gz":"/usr/share/man/man1/java.1.gz","java.ja.1.gz":"/usr/share/man/ja/man1/java.1.gz","jexec":"/usr/bin/jexec"},` +
+		`"Status":"auto","Best":"/usr/lib/jvm/java-21-openjdk-amd64/bin/java","Value":"/usr/lib/jvm/java-21-openjdk-amd64/bin/java",` +
+		`"Alternatives":[{"Path":"/usr/lib/jvm/java-21-openjdk-amd64/bin/java","Priority":2111,` +
+		`"Slaves":{"java.1.gz":"/usr/lib/jvm/java-21-openjdk-amd64/man/man1/java.1.gz","jexec":"/usr/lib/jvm/java-21-openjdk-amd64/lib/jexec"}}]}`
+
+	// Map iteration order is randomized, so marshal several times.
+	for range 10 {
+		result, err := json.Marshal(a)
+		assert.NoError(t, err)
+		assert.Equal(t, expected, string(result))
+	}
+}
+
+func Test_Parser_Lenient_EqualsSeparator(t *testing.T) {
+	t.Parallel()
+
+	input := `Name: java
+Link= /usr/bin/java
+Status = auto
+Best: /usr/lib/jvm/java-21-openjdk-amd64/bin/java
+Value: /usr/lib/jvm/java-21-openjdk-amd64/bin/java
+
+Alternative: /usr/lib/jvm/java-21-openjdk-amd64/bin/java
+Priority= 2111
+
+Alternative: /usr/lib/jvm/java-8-openjdk-amd64/jre/bin/java
+Priority=1081
+`
+
+	parser := queryalternatives.NewParser(strings.NewReader(input))
+	parser.Lenient = true
+	result, err := parser.Parse()
+	assert.NoError(t, err)
+	assert.Equal(t, "/usr/bin/java", result.Link)
+	assert.Equal(t, queryalternatives.StatusAuto, result.Status)
+	if assert.Len(t, result.Alternatives, 2) {
+		assert.Equal(t, 2111, result.Alternatives[0].Priority)
+		assert.Equal(t, 1081, result.Alternatives[1].Priority)
+	}
+
+	// The colon takes precedence over the equals sign.
+	parser = queryalternatives.NewParser(strings.NewReader("Name: a=b\nX-Opts=foo:bar\n"))
+	parser.Lenient = true
+	result, err = parser.Parse()
+	assert.NoError(t, err)
+	assert.Equal(t, "a=b", result.Name)
+	assert.Equal(t, map[string][]string{"X-Opts=foo": {"bar"}}, result.Unknown)
+
+	result, err = queryalternatives.ParseString(input)
+	assert.Nil(t, result)
+	var parseErr *queryalternatives.ParseError
+	if assert.ErrorAs(t, err, &parseErr) {
+		assert.Equal(t, queryalternatives.CodeMalformedLine, parseErr.Code)
+		assert.Equal(t, 2, parseErr.Line)
+	}
+}
+
+func Test_Parser_Lenient_ModeAlias(t *testing.T) {
+	t.Parallel()
+
+	input := `Name: java
+Link: /usr/bin/java
+Mode: manual
+Best: /usr/lib/jvm/java-21-openjdk-amd64/bin/java
+Value: /usr/lib/jvm/java-8-openjdk-amd64/jre/bin/java
+`
+
+	parser := queryalternatives.NewParser(strings.NewReader(input))
+	parser.Lenient = true
+	result, err := parser.Parse()
+	assert.NoError(t, err)
+	assert.Equal(t, queryalternatives.StatusManual, result.Status)
+
+	result, err = queryalternatives.ParseString(input)
+	assert.Nil(t, result)
+	var parseErr *queryalternatives.ParseError
+	if assert.ErrorAs(t, err, &parseErr) {
+		assert.Equal(t, "unexpected key: Mode", parseErr.Message)
+		assert.Equal(t, 3, parseErr.Line)
+	}
+}
+
+func Test_Parser_LinePrefix(t *testing.T) {
+	t.Parallel()
+
+	input := `Oct 16 12:00:00 host backup[123]: Name: java
+Oct 16 12:00:00 host backup[123]: Link: /usr/bin/java
+Oct 16 12:00:00 host backup[123]: Slaves:
+Oct 16 12:00:00 host backup[123]:  java.1.gz /usr/share/man/man1/java.1.gz
+Oct 16 12:00:00 host backup[123]: Status: auto
+Oct 16 12:00:00 host backup[123]: Best: /usr/lib/jvm/java-21-openjdk-amd64/bin/java
+Oct 16 12:00:00 host backup[123]: Value: /usr/lib/jvm/java-21-openjdk-amd64/bin/java
+Oct 16 12:00:00 host backup[123]:
+Oct 16 12:00:00 host backup[123]: Alternative: /usr/lib/jvm/java-21-openjdk-amd64/bin/java
+Oct 16 12:00:00 host backup[123]: Priority: 2111
+Oct 16 12:00:00 host backup[123]: Slaves:
+Oct 16 12:00:01 host backup[123]:  java.1.gz /usr/lib/jvm/java-21-openjdk-amd64/man/man1/java.1.gz
+
+Oct 16 12:00:01 host backup[123]: Name: editor
+Link: /usr/bin/editor
+Oct 16 12:00:01 host backup[123]: Status: auto
+Oct 16 12:00:01 host backup[123]: Value: none
+`
+
+	parser := queryalternatives.NewParser(strings.NewReader(input))
+	parser.LinePrefix = regexp.MustCompile(`^\w{3} \d+ [\d:]+ \S+ \S+: ?`)
+	result, err := parser.ParseAll()
+	assert.NoError(t, err)
+	if !assert.Len(t, result, 2) {
+		return
+	}
+
+	assert.Equal(t, "java", result[0].Name)
+	assert.Equal(t, map[string]string{"java.1.gz": "/usr/share/man/man1/java.1.gz"}, result[0].Slaves)
+	assert.Equal(t, queryalternatives.StatusAuto, result[0].Status)
+	assert.Equal(t, []queryalternatives.Alternative{
+		{
+			Path:     "/usr/lib/jvm/java-21-openjdk-amd64/bin/java",
+			Priority: 2111,
+			Slaves:   map[string]string{"java.1.gz": "/usr/lib/jvm/java-21-openjdk-amd64/man/man1/java.1.gz"},
+		},
+	}, result[0].Alternatives)
+
+	assert.Equal(t, "editor", result[1].Name)
+	assert.Equal(t, "/usr/bin/editor", result[1].Link)
+	assert.Equal(t, "none", result[1].Value)
+
+	// Without LinePrefix, the timestamp is taken as a key.
+	_, err = queryalternatives.ParseString(input)
+	assert.Error(t, err)
+}
+
+func Test_Parser_ParseHeader(t *testing.T) {
+	t.Parallel()
+
+	input := `Name: java
+Link: /usr/bin/java
+Slaves:
+ java.1.gz /usr/share/man/man1/java.1.gz
+Status: auto
+Best: /usr/lib/jvm/java-21-openjdk-amd64/bin/java
+Value: /usr/lib/jvm/java-21-openjdk-amd64/bin/java
+
+Alternative: /usr/lib/jvm/java-21-openjdk-amd64/bin/java
+Priority: 2111
+
+Alternative: /usr/lib/jvm/java-8-openjdk-amd64/jre/bin/java
+Priority: 1081
+`
+
+	parser := queryalternatives.NewParser(strings.NewReader(input))
+	header, err := parser.ParseHeader()
+	assert.NoError(t, err)
+	assertEqualGroup(t, &queryalternatives.Alternatives{
+		Name: "java",
+		Link: "/usr/bin/java",
+		Slaves: map[string]string{
+			"java.1.gz": "/usr/share/man/man1/java.1.gz",
+		},
+		Status:       queryalternatives.StatusAuto,
+		Best:         "/usr/lib/jvm/java-21-openjdk-amd64/bin/java",
+		Value:        "/usr/lib/jvm/java-21-openjdk-amd64/bin/java",
+		Alternatives: []queryalternatives.Alternative{},
+	}, header)
+
+	next, err := parser.R.Peek(len("Alternative:"))
+	assert.NoError(t, err)
+	assert.Equal(t, "Alternative:", string(next))
+
+	result, err := parser.Parse()
+	assert.NoError(t, err)
+	expected, err := queryalternatives.ParseString(input)
+	assert.NoError(t, err)
+	assert.Equal(t, expected, result)
+
+	// The header returned by ParseHeader is not modified by Parse.
+	assert.Empty(t, header.Alternatives)
+}
+
+func Test_Parser_ParseHeader_EmptyGroup(t *testing.T) {
+	t.Parallel()
+
+	input := `Name: empty
+Link: /usr/bin/empty
+Status: auto
+Value: none
+
+Name: java
+Link: /usr/bin/java
+Status: auto
+Best: /usr/lib/jvm/java-21-openjdk-amd64/bin/java
+Value: /usr/lib/jvm/java-21-openjdk-amd64/bin/java
+
+Alternative: /usr/lib/jvm/java-21-openjdk-amd64/bin/java
+Priority: 2111
+`
+
+	parser := queryalternatives.NewParser(strings.NewReader(input))
+	header, err := parser.ParseHeader()
+	assert.NoError(t, err)
+	assert.Equal(t, "empty", header.Name)
+	assert.Equal(t, "none", header.Value)
+
+	empty, err := parser.Decode()
+	assert.NoError(t, err)
+	assert.Equal(t, header, empty)
+
+	header, err = parser.ParseHeader()
+	assert.NoError(t, err)
+	assert.Equal(t, "java", header.Name)
+	assert.Empty(t, header.Alternatives)
+
+	java, err := parser.Decode()
+	assert.NoError(t, err)
+	assert.Equal(t, "java", java.Name)
+	assert.Len(t, java.Alternatives, 1)
+
+	_, err = parser.ParseHeader()
+	assert.Equal(t, io.EOF, err)
+}
+
+func Test_Parser_ParseHeader_Independent(t *testing.T) {
+	t.Parallel()
+
+	input := `Name: java
+Link: /usr/bin/java
+Slaves:
+ java.1.gz /usr/share/man/man1/java.1.gz
+Status: auto
+Best: /usr/lib/jvm/java-21-openjdk-amd64/bin/java
+Value: /usr/lib/jvm/java-21-openjdk-amd64/bin/java
+X-Vendor: Debian
+
+Alternative: /usr/lib/jvm/java-21-openjdk-amd64/bin/java
+Priority: 2111
+`
+
+	parser := queryalternatives.NewParser(strings.NewReader(input))
+	parser.Lenient = true
+	header, err := parser.ParseHeader()
+	if !assert.NoError(t, err) {
+		return
+	}
+	group, err := parser.Parse()
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	group.Slaves["jexec"] = "/usr/bin/jexec"
+	group.Unknown["X-Vendor"][0] = "Ubuntu"
+	assert.Equal(t, map[string]string{"java.1.gz": "/usr/share/man/man1/java.1.gz"}, header.Slaves)
+	assert.Equal(t, map[string][]string{"X-Vendor": {"Debian"}}, header.Unknown)
+
+	header.Slaves["java.ja.1.gz"] = "/usr/share/man/ja/man1/java.1.gz"
+	header.Unknown["X-Origin"] = []string{"test"}
+	assert.NotContains(t, group.Slaves, "java.ja.1.gz")
+	assert.NotContains(t, group.Unknown, "X-Origin")
+}
+
+func Test_Parser_ParseHeader_Error(t *testing.T) {
+	t.Parallel()
+
+	parser := queryalternatives.NewParser(strings.NewReader("Name: java\nStatus: sometimes\n\nAlternative: /usr/bin/java\n"))
+	header, err := parser.ParseHeader()
+	assert.Nil(t, header)
+	var parseErr *queryalternatives.ParseError
+	if assert.ErrorAs(t, err, &parseErr) {
+		assert.Equal(t, 2, parseErr.Line)
+	}
+}
+
+func Test_ParseError_Is(t *testing.T) {
+	t.Parallel()
+
+	sentinels := []error{
+		queryalternatives.ErrMalformedLine,
+		queryalternatives.ErrMalformedSlaves,
+		queryalternatives.ErrUnexpectedKey,
+		queryalternatives.ErrInvalidPriority,
+		queryalternatives.ErrNegativePriority,
+		queryalternatives.ErrInvalidStatus,
+		queryalternatives.ErrTooManySlaves,
+		queryalternatives.ErrUnexpectedEOF,
+	}
+
+	tests := []struct {
+		name     string
+		parse    func() error
+		expected error
+	}{
+		{
+			name: "malformed line",
+			parse: func() error {
+				_, err := queryalternatives.ParseString("Name java\n")
+				return err
+			},
+			expected: queryalternatives.ErrMalformedLine,
+		},
+		{
+			name: "malformed slaves",
+			parse: func() error {
+				_, err := queryalternatives.ParseString("Name: java\nSlaves:\n java.1.gz\n")
+				return err
+			},
+			expected: queryalternatives.ErrMalformedSlaves,
+		},
+		{
+			name: "unexpected key",
+			parse: func() error {
+				_, err := queryalternatives.ParseString("Name: java\nColor: blue\n")
+				return err
+			},
+			expected: queryalternatives.ErrUnexpectedKey,
+		},
+		{
+			name: "invalid priority",
+			parse: func() error {
+				_, err := queryalternatives.ParseString("Name: java\nAlternative: /usr/bin/java\nPriority: high\n")
+				return err
+			},
+			expected: queryalternatives.ErrInvalidPriority,
+		},
+		{
+			name: "negative priority",
+			parse: func() error {
+				parser := queryalternatives.NewParser(strings.NewReader("Name: java\nAlternative: /usr/bin/java\nPriority: -1\n"))
+				parser.RejectNegativePriority = true
+				_, err := parser.Parse()
+				return err
+			},
+			expected: queryalternatives.ErrNegativePriority,
+		},
+		{
+			name: "invalid status",
+			parse: func() error {
+				_, err := queryalternatives.ParseString("Name: java\nStatus: sometimes\n")
+				return err
+			},
+			expected: queryalternatives.ErrInvalidStatus,
+		},
+		{
+			name: "too many slaves",
+			parse: func() error {
+				parser := queryalternatives.NewParser(strings.NewReader("Name: java\nSlaves:\n a /a\n b /b\n"))
+				parser.MaxSlavesPerBlock = 1
+				_, err := parser.Parse()
+				return err
+			},
+			expected: queryalternatives.ErrTooManySlaves,
+		},
+		{
+			name: "unexpected end of file",
+			parse: func() error {
+				_, err := queryalternatives.ParseAdminFile(strings.NewReader("auto\n"))
+				return err
+			},
+			expected: queryalternatives.ErrUnexpectedEOF,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			err := test.parse()
+			for _, sentinel := range sentinels {
+				assert.Equal(t, sentinel == test.expected, errors.Is(err, sentinel), "%v", sentinel)
+			}
+		})
+	}
+
+	assert.False(t, errors.Is(&queryalternatives.ParseError{Message: "other"}, queryalternatives.ErrMalformedLine))
+}
+
+func Test_Parser_UnknownKeys(t *testing.T) {
+	t.Parallel()
+
+	input := `Name: java
+Link: /usr/bin/java
+Tag: jdk
+Status: auto
+Tag: lts
+Value: /usr/lib/jvm/java-21-openjdk-amd64/bin/java
+
+Alternative: /usr/lib/jvm/java-21-openjdk-amd64/bin/java
+Priority: 2111
+Vendor: openjdk
+`
+
+	tests := []struct {
+		name    string
+		lenient bool
+		keepRaw bool
+	}{
+		{name: "lenient", lenient: true},
+		{name: "keep raw", keepRaw: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			parser := queryalternatives.NewParser(strings.NewReader(input))
+			parser.Lenient = test.lenient
+			parser.KeepRaw = test.keepRaw
+			result, err := parser.Parse()
+			assert.NoError(t, err)
+			assert.Equal(t, map[string][]string{
+				"Tag":    {"jdk", "lts"},
+				"Vendor": {"openjdk"},
+			}, result.Unknown)
+			assert.Equal(t, queryalternatives.StatusAuto, result.Status)
+			assert.Len(t, result.Alternatives, 1)
+		})
+	}
+
+	_, err := queryalternatives.ParseString(input)
+	assert.ErrorIs(t, err, queryalternatives.ErrUnexpectedKey)
+}
+
+func Test_ParseRecords(t *testing.T) {
+	t.Parallel()
+
+	records := func(pairs ...string) iter.Seq2[string, string] {
+		return func(yield func(string, string) bool) {
+			for i := 0; i+1 < len(pairs); i += 2 {
+				if !yield(pairs[i], pairs[i+1]) {
+					return
+				}
+			}
+		}
+	}
+
+	result, err := queryalternatives.ParseRecords(records(
+		"Name", "editor",
+		"Link", "/usr/bin/editor",
+		"Slaves", "editor.1.gz /usr/share/man/man1/editor.1.gz\neditor.fr.1.gz /usr/share/man/fr/man1/editor.1.gz",
+		"Status", "auto",
+		"Best", "/usr/bin/vim.basic",
+		"Value", "/usr/bin/vim.basic",
+		"Alternative", "/bin/nano",
+		"Priority", "40",
+		"Slaves", "editor.1.gz /usr/share/man/man1/nano.1.gz",
+		"Alternative", "/usr/bin/vim.basic",
+		"Priority", "50",
+	))
+	assert.NoError(t, err)
+	assertEqualGroup(t, &queryalternatives.Alternatives{
+		Name: "editor",
+		Link: "/usr/bin/editor",
+		Slaves: map[string]string{
+			"editor.1.gz":    "/usr/share/man/man1/editor.1.gz",
+			"editor.fr.1.gz": "/usr/share/man/fr/man1/editor.1.gz",
+		},
+		Status: queryalternatives.StatusAuto,
+		Best:   "/usr/bin/vim.basic",
+		Value:  "/usr/bin/vim.basic",
+		Alternatives: []queryalternatives.Alternative{
+			{
+				Path:     "/bin/nano",
+				Priority: 40,
+				Slaves: map[string]string{
+					"editor.1.gz": "/usr/share/man/man1/nano.1.gz",
+				},
+			},
+			{
+				Path:     "/usr/bin/vim.basic",
+				Priority: 50,
+				Slaves:   map[string]string{},
+			},
+		},
+	}, result)
+
+	_, err = queryalternatives.ParseRecords(records(
+		"Name", "editor",
+		"Alternative", "/bin/nano",
+		"Priority", "high",
+	))
+	var parseErr *queryalternatives.ParseError
+	if assert.ErrorAs(t, err, &parseErr) {
+		assert.Equal(t, queryalternatives.CodeInvalidPriority, parseErr.Code)
+		assert.Equal(t, 3, parseErr.Line)
+	}
+}
+
+func Test_ParseString_NoSlaves(t *testing.T) {
+	t.Parallel()
+
+	result, err := queryalternatives.ParseString(`Name: editor
+Link: /usr/bin/editor
+Status: auto
+Best: /usr/bin/vim.basic
+Value: /usr/bin/vim.basic
+
+Alternative: /bin/nano
+Priority: 40
+
+Alternative: /usr/bin/vim.basic
+Priority: 50
+`)
+	assert.NoError(t, err)
+
+	// Slaves is empty but not nil both at the group level and for each
+	// alternative.
+	assert.NotNil(t, result.Slaves)
+	assert.Empty(t, result.Slaves)
+	if assert.Len(t, result.Alternatives, 2) {
+		for _, alt := range result.Alternatives {
+			assert.NotNil(t, alt.Slaves)
+			assert.Empty(t, alt.Slaves)
+		}
+	}
+}
+
+func Test_ParseString_TrailingWhitespace(t *testing.T) {
+	t.Parallel()
+
+	result, err := queryalternatives.ParseString("Name: java  \n" +
+		"Link: /usr/bin/java\t\n" +
+		"Slaves:\n" +
+		" java.1.gz /usr/share/man/man1/java.1.gz \n" +
+		"Status: auto   \n" +
+		"Best: /usr/lib/jvm/java-21-openjdk-amd64/bin/java \r\n" +
+		"Value: /usr/lib/jvm/java-21-openjdk-amd64/bin/java  \n" +
+		"\n" +
+		"Alternative: /usr/lib/jvm/java-21-openjdk-amd64/bin/java \n" +
+		"Priority: 2111 \n")
+	assert.NoError(t, err)
+	assert.Equal(t, "java", result.Name)
+	assert.Equal(t, "/usr/bin/java", result.Link)
+	assert.Equal(t, queryalternatives.StatusAuto, result.Status)
+	assert.Equal(t, "/usr/lib/jvm/java-21-openjdk-amd64/bin/java", result.Best)
+	assert.Equal(t, "/usr/lib/jvm/java-21-openjdk-amd64/bin/java", result.Value)
+	if assert.Len(t, result.Alternatives, 1) {
+		assert.Equal(t, "/usr/lib/jvm/java-21-openjdk-amd64/bin/java", result.Alternatives[0].Path)
+		assert.Equal(t, 2111, result.Alternatives[0].Priority)
+	}
+
+	// Lines of multi-line values are kept as is.
+	assert.Equal(t, map[string]string{
+		"java.1.gz": "/usr/share/man/man1/java.1.gz ",
+	}, result.Slaves)
+}
+
+func Test_ParseReaders(t *testing.T) {
+	t.Parallel()
+
+	java := `Name: java
+Link: /usr/bin/java
+Status: auto
+Best: /usr/lib/jvm/java-21-openjdk-amd64/bin/java
+Value: /usr/lib/jvm/java-21-openjdk-amd64/bin/java
+
+Alternative: /usr/lib/jvm/java-21-openjdk-amd64/bin/java
+Priority: 2111
+`
+	editors := `Name: editor
+Link: /usr/bin/editor
+Status: manual
+Value: /bin/nano
+
+Alternative: /bin/nano
+Priority: 40
+
+Name: vi
+Link: /usr/bin/vi
+Status: auto
+Value: /usr/bin/vim.basic
+
+Alternative: /usr/bin/vim.basic
+Priority: 30
+`
+	broken := `Name: pager
+Link: /usr/bin/pager
+Status: sideways
+`
+
+	result, err := queryalternatives.ParseReaders(
+		strings.NewReader(java),
+		strings.NewReader(broken),
+		strings.NewReader(editors),
+		strings.NewReader("garbage\n"),
+	)
+	names := make([]string, 0, len(result))
+	for _, g := range result {
+		names = append(names, g.Name)
+	}
+	assert.Equal(t, []string{"java", "editor", "vi"}, names)
+
+	assert.ErrorIs(t, err, queryalternatives.ErrInvalidStatus)
+	assert.ErrorIs(t, err, queryalternatives.ErrMalformedLine)
+	assert.ErrorContains(t, err, "reader 1: ")
+	assert.ErrorContains(t, err, "reader 3: ")
+
+	result, err = queryalternatives.ParseReaders(strings.NewReader(java), strings.NewReader(editors))
+	assert.NoError(t, err)
+	assert.Len(t, result, 3)
+}
+
+func Test_ParseString_WSLPaths(t *testing.T) {
+	t.Parallel()
+
+	result, err := queryalternatives.ParseString(`Name: java
+Link: /usr/bin/java
+Slaves:
+ java.1.gz /mnt/c/Program Files/Java/jdk-21/man/man1/java.1.gz
+Status: manual
+Best: /mnt/c/Program Files/Java/jdk-21/bin/java.exe
+Value: C:\Program Files\Java\jdk-17\bin\java.exe
+
+Alternative: /mnt/c/Program Files/Java/jdk-21/bin/java.exe
+Priority: 2100
+Slaves:
+ java.1.gz /mnt/c/Program Files/Java/jdk-21/man/man1/java.1.gz
+
+Alternative: C:\Program Files\Java\jdk-17\bin\java.exe
+Priority: 1700
+`)
+	assert.NoError(t, err)
+	assert.Equal(t, `C:\Program Files\Java\jdk-17\bin\java.exe`, result.Value)
+	assert.Equal(t, map[string]string{
+		"java.1.gz": "/mnt/c/Program Files/Java/jdk-21/man/man1/java.1.gz",
+	}, result.Slaves)
+	if assert.Len(t, result.Alternatives, 2) {
+		assert.Equal(t, "/mnt/c/Program Files/Java/jdk-21/bin/java.exe", result.Alternatives[0].Path)
+		assert.Equal(t, `C:\Program Files\Java\jdk-17\bin\java.exe`, result.Alternatives[1].Path)
+	}
+	assert.Empty(t, result.Validate())
+}
+
+func Test_Parser_ParseUntil(t *testing.T) {
+	t.Parallel()
+
+	input := `Name: editor
+Link: /usr/bin/editor
+Status: manual
+Best: /usr/bin/vim.basic
+Value: /bin/nano
+
+Alternative: /bin/nano
+Priority: 40
+
+Name: java
+Link: /usr/bin/java
+Slaves:
+ java.1.gz /usr/share/man/man1/java.1.gz
+Status: auto
+Best: /usr/lib/jvm/java-21-openjdk-amd64/bin/java
+Value: /usr/lib/jvm/java-21-openjdk-amd64/bin/java
+
+Alternative: /usr/lib/jvm/java-21-openjdk-amd64/bin/java
+Priority: 2111
+Slaves:
+ java.1.gz /usr/lib/jvm/java-21-openjdk-amd64/man/man1/java.1.gz
+
+Name: pager
+Link: /usr/bin/pager
+Value: none
+`
+
+	parser := queryalternatives.NewParser(strings.NewReader(input))
+
+	result, err := parser.ParseUntil("Status")
+	if assert.NoError(t, err) {
+		assert.Equal(t, "editor", result.Name)
+		assert.Equal(t, queryalternatives.StatusManual, result.Status)
+		assert.Empty(t, result.Value)
+		assert.Empty(t, result.Alternatives)
+	}
+
+	result, err = parser.ParseUntil("Status")
+	if assert.NoError(t, err) {
+		assert.Equal(t, "java", result.Name)
+		assert.Equal(t, queryalternatives.StatusAuto, result.Status)
+		assert.Equal(t, map[string]string{"java.1.gz": "/usr/share/man/man1/java.1.gz"}, result.Slaves)
+		assert.Empty(t, result.Alternatives)
+	}
+
+	// Without Status, the whole group is read.
+	result, err = parser.ParseUntil("Status")
+	if assert.NoError(t, err) {
+		assert.Equal(t, "pager", result.Name)
+		assert.Equal(t, "none", result.Value)
+	}
+
+	_, err = parser.ParseUntil("Status")
+	assert.Equal(t, io.EOF, err)
+
+	// Decode continues with the next group after ParseUntil.
+	parser = queryalternatives.NewParser(strings.NewReader(input))
+	_, err = parser.ParseUntil("Link")
+	assert.NoError(t, err)
+	result, err = parser.Decode()
+	if assert.NoError(t, err) {
+		assert.Equal(t, "java", result.Name)
+		assert.Len(t, result.Alternatives, 1)
+	}
+}
@@ -0,0 +1,183 @@
+package queryalternatives
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ActionKind is the kind of an Action.
+type ActionKind string
+
+const (
+	// ActionSet selects a path with `update-alternatives --set`.
+	ActionSet ActionKind = "set"
+	// ActionAuto switches a group to auto mode with `update-alternatives --auto`.
+	ActionAuto ActionKind = "auto"
+)
+
+// Action is a modification of an alternatives group.
+type Action struct {
+	// Kind is the kind of the action.
+	Kind ActionKind
+	// Name is the name of the group.
+	Name string
+	// Path is the path to select. It is only used by ActionSet.
+	Path string
+}
+
+// desiredEntry is an entry of a desired-state document.
+type desiredEntry struct {
+	Name string `yaml:"name"`
+	Mode string `yaml:"mode"`
+	Path string `yaml:"path"`
+}
+
+// LoadDesired parses a desired-state document, which is a YAML or JSON list
+// of entries with "name", "mode" ("auto" or "manual") and "path" fields.
+// The path is required in manual mode and ignored in auto mode.
+func LoadDesired(r io.Reader) ([]Selection, error) {
+	var entries []desiredEntry
+	decoder := yaml.NewDecoder(r)
+	decoder.KnownFields(true)
+	if err := decoder.Decode(&entries); err != nil && err != io.EOF {
+		return nil, fmt.Errorf("error loading desired state: %w", err)
+	}
+
+	result := make([]Selection, 0, len(entries))
+	for i, entry := range entries {
+		if entry.Name == "" {
+			return nil, fmt.Errorf("error loading desired state: entry %d: missing name", i)
+		}
+		selection := Selection{
+			Name: entry.Name,
+			Mode: Status(entry.Mode),
+		}
+		switch selection.Mode {
+		case StatusAuto:
+		case StatusManual:
+			if entry.Path == "" {
+				return nil, fmt.Errorf("error loading desired state: %s: missing path in manual mode", entry.Name)
+			}
+			selection.Current = entry.Path
+		default:
+			return nil, fmt.Errorf("error loading desired state: %s: invalid mode: %q", entry.Name, entry.Mode)
+		}
+		result = append(result, selection)
+	}
+	return result, nil
+}
+
+// Plan returns the actions which converge current to desired.
+// The result is empty if current is already in the desired state.
+func Plan(current *Alternatives, desired Selection) ([]Action, error) {
+	if current.Name != desired.Name {
+		return nil, fmt.Errorf("group name mismatch: %s != %s", current.Name, desired.Name)
+	}
+
+	switch desired.Mode {
+	case StatusAuto:
+		if current.satisfies(desired) {
+			return []Action{}, nil
+		}
+		return []Action{{Kind: ActionAuto, Name: desired.Name}}, nil
+	case StatusManual:
+		if desired.Current == "" {
+			return nil, fmt.Errorf("%s: no path to select in manual mode", desired.Name)
+		}
+		if !current.hasAlternative(desired.Current) {
+			return nil, fmt.Errorf("%s: %s is not a registered alternative", desired.Name, desired.Current)
+		}
+		if current.satisfies(desired) {
+			return []Action{}, nil
+		}
+		return []Action{{Kind: ActionSet, Name: desired.Name, Path: desired.Current}}, nil
+	}
+	return nil, fmt.Errorf("%s: invalid mode: %q", desired.Name, desired.Mode)
+}
+
+func (a *Alternatives) hasAlternative(path string) bool {
+	for _, alt := range a.Alternatives {
+		if alt.Path == path {
+			return true
+		}
+	}
+	return false
+}
+
+// PlanInventory returns the actions which converge the groups in current,
+// such as the result of Inventory, to desired.
+// A desired group which is not in current is reported with a NotFoundError,
+// and other planning errors are reported as by Plan. The errors are joined
+// into the returned error, and the actions for the other groups are still
+// returned.
+func PlanInventory(current []*Alternatives, desired []Selection) ([]Action, error) {
+	index := Index(current)
+
+	actions := make([]Action, 0)
+	var errs []error
+	for _, d := range desired {
+		group, ok := index[d.Name]
+		if !ok {
+			errs = append(errs, &NotFoundError{
+				QueryError: QueryError{
+					Message: "no alternatives for " + d.Name,
+				},
+			})
+			continue
+		}
+		planned, err := Plan(group, d)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		actions = append(actions, planned...)
+	}
+	return actions, errors.Join(errs...)
+}
+
+// Execute performs action.
+func (q *Querier) Execute(ctx context.Context, action Action) error {
+	switch action.Kind {
+	case ActionSet:
+		return q.Set(ctx, action.Name, action.Path)
+	case ActionAuto:
+		return q.Auto(ctx, action.Name)
+	}
+	return fmt.Errorf("%s: unknown action: %q", action.Name, action.Kind)
+}
+
+// Reconcile queries each group in desired, plans the actions needed to
+// converge them, and executes the actions unless q.DryRun is set.
+// If q is nil, the Querier carried by ctx is used (see WithQuerier).
+// All actions are planned before any of them is executed, so a planning
+// error leaves the system untouched. The planned actions are returned even
+// if executing one of them fails.
+func Reconcile(ctx context.Context, q *Querier, desired []Selection) ([]Action, error) {
+	if q == nil {
+		q = querierFrom(ctx)
+	}
+
+	actions := make([]Action, 0)
+	for _, d := range desired {
+		current, err := q.Query(ctx, d.Name)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", d.Name, err)
+		}
+		planned, err := Plan(current, d)
+		if err != nil {
+			return nil, err
+		}
+		actions = append(actions, planned...)
+	}
+
+	for _, action := range actions {
+		if err := q.Execute(ctx, action); err != nil {
+			return actions, fmt.Errorf("%s: %w", action.Name, err)
+		}
+	}
+	return actions, nil
+}
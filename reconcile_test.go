@@ -0,0 +1,243 @@
+package queryalternatives_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/kofuk/go-queryalternatives"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_LoadDesired(t *testing.T) {
+	t.Parallel()
+
+	expected := []queryalternatives.Selection{
+		{
+			Name:    "java",
+			Mode:    queryalternatives.StatusManual,
+			Current: "/usr/lib/jvm/java-8-openjdk-amd64/jre/bin/java",
+		},
+		{
+			Name: "editor",
+			Mode: queryalternatives.StatusAuto,
+		},
+	}
+
+	tests := []struct {
+		name  string
+		input string
+	}{
+		{
+			name: "yaml",
+			input: `- name: java
+  mode: manual
+  path: /usr/lib/jvm/java-8-openjdk-amd64/jre/bin/java
+- name: editor
+  mode: auto
+`,
+		},
+		{
+			name: "json",
+			input: `[
+  {"name": "java", "mode": "manual", "path": "/usr/lib/jvm/java-8-openjdk-amd64/jre/bin/java"},
+  {"name": "editor", "mode": "auto"}
+]`,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			result, err := queryalternatives.LoadDesired(strings.NewReader(test.input))
+			assert.NoError(t, err)
+			assert.Equal(t, expected, result)
+		})
+	}
+}
+
+func Test_LoadDesired_Error(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name  string
+		input string
+	}{
+		{name: "missing name", input: `[{"mode": "auto"}]`},
+		{name: "invalid mode", input: `[{"name": "java", "mode": "sometimes"}]`},
+		{name: "manual without path", input: `[{"name": "java", "mode": "manual"}]`},
+		{name: "unknown field", input: `[{"name": "java", "mode": "auto", "priority": 1}]`},
+		{name: "not a list", input: `name: java`},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			result, err := queryalternatives.LoadDesired(strings.NewReader(test.input))
+			assert.Error(t, err)
+			assert.Nil(t, result)
+		})
+	}
+}
+
+func Test_Plan(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		current  *queryalternatives.Alternatives
+		desired  queryalternatives.Selection
+		expected []queryalternatives.Action
+		wantErr  bool
+	}{
+		{
+			name:     "already auto",
+			current:  newJava(),
+			desired:  queryalternatives.Selection{Name: "java", Mode: queryalternatives.StatusAuto},
+			expected: []queryalternatives.Action{},
+		},
+		{
+			name:    "switch to auto",
+			current: newEditor(),
+			desired: queryalternatives.Selection{Name: "editor", Mode: queryalternatives.StatusAuto},
+			expected: []queryalternatives.Action{
+				{Kind: queryalternatives.ActionAuto, Name: "editor"},
+			},
+		},
+		{
+			name:    "select path",
+			current: newJava(),
+			desired: queryalternatives.Selection{
+				Name:    "java",
+				Mode:    queryalternatives.StatusManual,
+				Current: "/usr/lib/jvm/java-8-openjdk-amd64/jre/bin/java",
+			},
+			expected: []queryalternatives.Action{
+				{Kind: queryalternatives.ActionSet, Name: "java", Path: "/usr/lib/jvm/java-8-openjdk-amd64/jre/bin/java"},
+			},
+		},
+		{
+			name:     "already selected",
+			current:  newEditor(),
+			desired:  queryalternatives.Selection{Name: "editor", Mode: queryalternatives.StatusManual, Current: "/bin/nano"},
+			expected: []queryalternatives.Action{},
+		},
+		{
+			name:    "unregistered path",
+			current: newEditor(),
+			desired: queryalternatives.Selection{Name: "editor", Mode: queryalternatives.StatusManual, Current: "/usr/bin/emacs"},
+			wantErr: true,
+		},
+		{
+			name:    "name mismatch",
+			current: newEditor(),
+			desired: queryalternatives.Selection{Name: "java", Mode: queryalternatives.StatusAuto},
+			wantErr: true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			result, err := queryalternatives.Plan(test.current, test.desired)
+			if test.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, test.expected, result)
+		})
+	}
+}
+
+func Test_PlanInventory(t *testing.T) {
+	t.Parallel()
+
+	current := []*queryalternatives.Alternatives{newJava(), newEditor()}
+
+	desired, err := queryalternatives.LoadDesired(strings.NewReader(`- name: java
+  mode: manual
+  path: /usr/lib/jvm/java-8-openjdk-amd64/jre/bin/java
+- name: editor
+  mode: manual
+  path: /bin/nano
+`))
+	require.NoError(t, err)
+
+	actions, err := queryalternatives.PlanInventory(current, desired)
+	assert.NoError(t, err)
+	assert.Equal(t, []queryalternatives.Action{
+		{Kind: queryalternatives.ActionSet, Name: "java", Path: "/usr/lib/jvm/java-8-openjdk-amd64/jre/bin/java"},
+	}, actions)
+
+	actions, err = queryalternatives.PlanInventory(current, []queryalternatives.Selection{
+		{Name: "pager", Mode: queryalternatives.StatusAuto},
+		{Name: "editor", Mode: queryalternatives.StatusAuto},
+		{Name: "java", Mode: queryalternatives.StatusManual, Current: "/usr/bin/not-registered"},
+	})
+	assert.ErrorIs(t, err, queryalternatives.ErrNotFound)
+	assert.ErrorContains(t, err, "pager")
+	assert.ErrorContains(t, err, "/usr/bin/not-registered")
+	assert.Equal(t, []queryalternatives.Action{
+		{Kind: queryalternatives.ActionAuto, Name: "editor"},
+	}, actions)
+}
+
+func Test_Reconcile(t *testing.T) {
+	t.Parallel()
+
+	desired, err := queryalternatives.LoadDesired(strings.NewReader(`- name: java
+  mode: manual
+  path: /usr/lib/jvm/java-8-openjdk-amd64/jre/bin/java
+- name: editor
+  mode: manual
+  path: /bin/nano
+`))
+	require.NoError(t, err)
+
+	expected := []queryalternatives.Action{
+		{Kind: queryalternatives.ActionSet, Name: "java", Path: "/usr/lib/jvm/java-8-openjdk-amd64/jre/bin/java"},
+	}
+
+	tests := []struct {
+		name           string
+		dryRun         bool
+		expectedWrites int
+	}{
+		{name: "dry run", dryRun: true, expectedWrites: 0},
+		{name: "apply", dryRun: false, expectedWrites: 1},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			system := newFakeSystem(newJava(), newEditor())
+			q := &queryalternatives.Querier{Runner: system.Run, DryRun: test.dryRun}
+
+			actions, err := queryalternatives.Reconcile(context.Background(), q, desired)
+			assert.NoError(t, err)
+			assert.Equal(t, expected, actions)
+			assert.Equal(t, test.expectedWrites, system.writes)
+		})
+	}
+}
+
+func Test_Reconcile_UnknownGroup(t *testing.T) {
+	t.Parallel()
+
+	system := newFakeSystem(newJava())
+	q := &queryalternatives.Querier{Runner: system.Run}
+
+	actions, err := queryalternatives.Reconcile(context.Background(), q, []queryalternatives.Selection{
+		{Name: "java", Mode: queryalternatives.StatusManual, Current: "/usr/lib/jvm/java-8-openjdk-amd64/jre/bin/java"},
+		{Name: "nosuch", Mode: queryalternatives.StatusAuto},
+	})
+	assert.ErrorIs(t, err, queryalternatives.ErrNotFound)
+	assert.Nil(t, actions)
+	assert.Equal(t, 0, system.writes)
+}
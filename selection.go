@@ -0,0 +1,288 @@
+package queryalternatives
+
+import (
+	"fmt"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// Selection is the minimal state of an alternatives group: which
+// alternative is currently selected and how.
+type Selection struct {
+	// Name is the name of the alternatives group.
+	Name string
+	// Mode is the status of the group.
+	Mode Status
+	// Current is the path to the currently selected alternative.
+	// It is empty if no alternative is selected.
+	Current string
+	// Best is the path to the best alternative.
+	Best string
+}
+
+// AsSelection returns the selection state of the group.
+// Value "none" is mapped to an empty Current.
+func (a *Alternatives) AsSelection() Selection {
+	current := a.Value
+	if isNone(current) {
+		current = ""
+	}
+	return Selection{
+		Name:    a.Name,
+		Mode:    a.Status,
+		Current: current,
+		Best:    a.Best,
+	}
+}
+
+// isNone reports whether v, a Value, means that no alternative is selected.
+func isNone(v string) bool {
+	return v == "" || v == "none"
+}
+
+// selected returns the currently selected alternative, or nil if no
+// alternative is selected or Value is not a registered alternative.
+func (a *Alternatives) selected() *Alternative {
+	if isNone(a.Value) {
+		return nil
+	}
+	return a.alternative(a.Value)
+}
+
+// Selected returns the currently selected alternative.
+// The second result is false if no alternative is selected (Value is empty
+// or "none") or Value is not a registered alternative.
+func (a *Alternatives) Selected() (*Alternative, bool) {
+	alt := a.selected()
+	return alt, alt != nil
+}
+
+// SelectedPath returns the path of the currently selected alternative.
+// Unlike Value, it is never "none": the second result is false if no
+// alternative is selected. Value is returned even if it is not a registered
+// alternative.
+func (a *Alternatives) SelectedPath() (string, bool) {
+	if isNone(a.Value) {
+		return "", false
+	}
+	return a.Value, true
+}
+
+// BestAlternative returns the best alternative.
+// The second result is false if Best is empty or not a registered
+// alternative.
+func (a *Alternatives) BestAlternative() (*Alternative, bool) {
+	if isNone(a.Best) {
+		return nil, false
+	}
+	alt := a.alternative(a.Best)
+	return alt, alt != nil
+}
+
+// DriftsFromBest reports whether the group is in auto mode but the selected
+// alternative is not the best one, which update-alternatives normally
+// prevents. A group without alternatives, whose Best is empty and Value is
+// "none", does not drift.
+func (a *Alternatives) DriftsFromBest() bool {
+	if a.Status != StatusAuto {
+		return false
+	}
+	if a.Best == "" {
+		return !isNone(a.Value)
+	}
+	return a.Value != a.Best
+}
+
+// AutoWouldChange reports whether switching the group to auto mode would
+// repoint the link, that is, whether the selected alternative differs from
+// the best one. This is the case for a manual selection other than the best
+// alternative, and for an auto group which has drifted from it.
+// If Best is empty, the alternative with the highest priority is considered
+// the best. It fails if there is no best alternative or Best is not a
+// registered alternative.
+func (a *Alternatives) AutoWouldChange() (bool, error) {
+	best := a.alternative(a.bestPath())
+	if best == nil {
+		return false, fmt.Errorf("%s: best alternative cannot be resolved", a.Name)
+	}
+	return a.Value != best.Path, nil
+}
+
+// AffectedByRemoval returns the alternatives whose path is one of paths or
+// is under one of them, such as a JDK directory about to be removed, in the
+// order of Alternatives. willBreak is true if the currently selected
+// alternative is among them, so that the link would dangle after removal.
+func (a *Alternatives) AffectedByRemoval(paths []string) (willBreak bool, affected []Alternative) {
+	removed := make([]string, 0, len(paths))
+	for _, p := range paths {
+		if p != "" {
+			removed = append(removed, filepath.Clean(p))
+		}
+	}
+
+	affected = make([]Alternative, 0)
+	for _, alt := range a.Alternatives {
+		path := filepath.Clean(alt.Path)
+		if !slices.ContainsFunc(removed, func(p string) bool {
+			return path == p || strings.HasPrefix(path, strings.TrimSuffix(p, "/")+"/")
+		}) {
+			continue
+		}
+		affected = append(affected, alt)
+		if !isNone(a.Value) && alt.Path == a.Value {
+			willBreak = true
+		}
+	}
+	return willBreak, affected
+}
+
+// SelectionState is the classification of the selection of a group returned
+// by Alternatives.SelectionState.
+type SelectionState int
+
+const (
+	// StateNone means no alternative is selected: Value is empty or "none".
+	StateNone SelectionState = iota
+	// StateDangling means Value is not a registered alternative, so the link
+	// points to a path which update-alternatives does not know.
+	StateDangling
+	// StateAutoBest means the group is in auto mode and the best alternative
+	// is selected, which is the normal state.
+	StateAutoBest
+	// StateAutoDrift means the group is in auto mode but an alternative other
+	// than the best one is selected (see DriftsFromBest).
+	StateAutoDrift
+	// StateManualBest means the group is in manual mode and the best
+	// alternative is selected, so switching to auto mode changes nothing.
+	StateManualBest
+	// StateManualBelowBest means the group is in manual mode and the selected
+	// alternative is not the best one and its priority is lower than or equal
+	// to the priority of the best one, typically a pin to an older version.
+	StateManualBelowBest
+	// StateManualAboveBest means the group is in manual mode and the priority
+	// of the selected alternative is higher than the priority of the best
+	// one, which happens when Best is out of date (see SelectedNewerThanBest).
+	StateManualAboveBest
+)
+
+func (s SelectionState) String() string {
+	switch s {
+	case StateNone:
+		return "none"
+	case StateDangling:
+		return "dangling"
+	case StateAutoBest:
+		return "auto-best"
+	case StateAutoDrift:
+		return "auto-drift"
+	case StateManualBest:
+		return "manual-best"
+	case StateManualBelowBest:
+		return "manual-below-best"
+	case StateManualAboveBest:
+		return "manual-above-best"
+	}
+	return fmt.Sprintf("SelectionState(%d)", int(s))
+}
+
+// SelectionState classifies the selection of the group. StateNone and
+// StateDangling take precedence over the states depending on the mode.
+// If Best is empty, the alternative with the highest priority is considered
+// the best.
+// It fails if Status is neither auto nor manual, or if the group is in
+// manual mode and the best alternative is not registered.
+func (a *Alternatives) SelectionState() (SelectionState, error) {
+	if a.Status != StatusAuto && a.Status != StatusManual {
+		return 0, fmt.Errorf("%s: invalid status: %q", a.Name, a.Status)
+	}
+
+	selected := a.selected()
+	switch {
+	case isNone(a.Value):
+		return StateNone, nil
+	case selected == nil:
+		return StateDangling, nil
+	case a.Status == StatusAuto:
+		if a.Value == a.bestPath() {
+			return StateAutoBest, nil
+		}
+		return StateAutoDrift, nil
+	}
+
+	best := a.alternative(a.bestPath())
+	switch {
+	case best == nil:
+		return 0, fmt.Errorf("%s: best alternative %s is not registered", a.Name, a.Best)
+	case selected.Path == best.Path:
+		return StateManualBest, nil
+	case selected.Priority > best.Priority:
+		return StateManualAboveBest, nil
+	}
+	return StateManualBelowBest, nil
+}
+
+// CandidateRow is a row of a table of the alternatives of a group, as
+// returned by Alternatives.CandidateRows.
+type CandidateRow struct {
+	// Path is the path to the alternative.
+	Path string
+	// Priority is the priority of the alternative.
+	Priority int
+	// Selected is true if the alternative is currently selected.
+	Selected bool
+	// Best is true if the alternative is the best one.
+	Best bool
+}
+
+// CandidateRows returns a row for each alternative in the order of
+// Alternatives, flagging the selected and the best ones, which may be the
+// same row. If Best is empty, the alternative with the highest priority is
+// flagged as the best.
+func (a *Alternatives) CandidateRows() []CandidateRow {
+	best := a.bestPath()
+	result := make([]CandidateRow, 0, len(a.Alternatives))
+	for _, alt := range a.Alternatives {
+		result = append(result, CandidateRow{
+			Path:     alt.Path,
+			Priority: alt.Priority,
+			Selected: !isNone(a.Value) && alt.Path == a.Value,
+			Best:     alt.Path == best,
+		})
+	}
+	return result
+}
+
+// Summary returns a one-line summary of the group suitable for logs, such as
+// "java: auto -> /usr/bin/java-21 (best /usr/bin/java-21, 2 alts)".
+// An empty Value or Best is shown as "none".
+func (a *Alternatives) Summary() string {
+	value := a.Value
+	if value == "" {
+		value = "none"
+	}
+	best := a.Best
+	if best == "" {
+		best = "none"
+	}
+	count := strconv.Itoa(len(a.Alternatives))
+
+	var b strings.Builder
+	b.Grow(len(a.Name) + len(a.Status) + len(value) + len(best) + len(count) + 24)
+	b.WriteString(a.Name)
+	b.WriteString(": ")
+	b.WriteString(string(a.Status))
+	b.WriteString(" -> ")
+	b.WriteString(value)
+	b.WriteString(" (best ")
+	b.WriteString(best)
+	b.WriteString(", ")
+	b.WriteString(count)
+	if len(a.Alternatives) == 1 {
+		b.WriteString(" alt)")
+	} else {
+		b.WriteString(" alts)")
+	}
+	return b.String()
+}
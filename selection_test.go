@@ -0,0 +1,454 @@
+package queryalternatives_test
+
+import (
+	"testing"
+
+	"github.com/kofuk/go-queryalternatives"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_AsSelection(t *testing.T) {
+	t.Parallel()
+
+	none := newJava()
+	none.Value = "none"
+	none.Best = ""
+	none.Alternatives = nil
+
+	tests := []struct {
+		name     string
+		input    *queryalternatives.Alternatives
+		expected queryalternatives.Selection
+	}{
+		{
+			name:  "auto",
+			input: newJava(),
+			expected: queryalternatives.Selection{
+				Name:    "java",
+				Mode:    queryalternatives.StatusAuto,
+				Current: "/usr/lib/jvm/java-21-openjdk-amd64/bin/java",
+				Best:    "/usr/lib/jvm/java-21-openjdk-amd64/bin/java",
+			},
+		},
+		{
+			name:  "manual",
+			input: newEditor(),
+			expected: queryalternatives.Selection{
+				Name:    "editor",
+				Mode:    queryalternatives.StatusManual,
+				Current: "/bin/nano",
+				Best:    "/usr/bin/vim.basic",
+			},
+		},
+		{
+			name:  "none",
+			input: none,
+			expected: queryalternatives.Selection{
+				Name: "java",
+				Mode: queryalternatives.StatusAuto,
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			assert.Equal(t, test.expected, test.input.AsSelection())
+		})
+	}
+}
+
+func Test_Summary(t *testing.T) {
+	t.Parallel()
+
+	none := newJava()
+	none.Value = "none"
+	none.Best = ""
+	none.Alternatives = nil
+	single := newJava()
+	single.Alternatives = single.Alternatives[:1]
+
+	tests := []struct {
+		name     string
+		input    *queryalternatives.Alternatives
+		expected string
+	}{
+		{
+			name:     "auto",
+			input:    newJava(),
+			expected: "java: auto -> /usr/lib/jvm/java-21-openjdk-amd64/bin/java (best /usr/lib/jvm/java-21-openjdk-amd64/bin/java, 2 alts)",
+		},
+		{
+			name:     "manual",
+			input:    newEditor(),
+			expected: "editor: manual -> /bin/nano (best /usr/bin/vim.basic, 2 alts)",
+		},
+		{
+			name:     "none",
+			input:    none,
+			expected: "java: auto -> none (best none, 0 alts)",
+		},
+		{
+			name:     "single",
+			input:    single,
+			expected: "java: auto -> /usr/lib/jvm/java-21-openjdk-amd64/bin/java (best /usr/lib/jvm/java-21-openjdk-amd64/bin/java, 1 alt)",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			assert.Equal(t, test.expected, test.input.Summary())
+		})
+	}
+}
+
+func Test_AutoWouldChange(t *testing.T) {
+	t.Parallel()
+
+	drifted := newJava()
+	drifted.Value = "/usr/lib/jvm/java-8-openjdk-amd64/jre/bin/java"
+	manualBest := newEditor()
+	manualBest.Value = "/usr/bin/vim.basic"
+	noBest := newJava()
+	noBest.Best = ""
+	empty := newJava()
+	empty.Best = ""
+	empty.Value = "none"
+	empty.Alternatives = nil
+	gone := newJava()
+	gone.Best = "/gone"
+
+	tests := []struct {
+		name     string
+		input    *queryalternatives.Alternatives
+		expected bool
+		err      bool
+	}{
+		{name: "auto clean", input: newJava(), expected: false},
+		{name: "auto drifted", input: drifted, expected: true},
+		{name: "manual", input: newEditor(), expected: true},
+		{name: "manual on best", input: manualBest, expected: false},
+		{name: "best from priority", input: noBest, expected: false},
+		{name: "no best", input: empty, err: true},
+		{name: "unregistered best", input: gone, err: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			result, err := test.input.AutoWouldChange()
+			if test.err {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, test.expected, result)
+		})
+	}
+}
+
+func Test_Resolvers_None(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		resolved func(a *queryalternatives.Alternatives) bool
+	}{
+		{
+			name: "Selected",
+			resolved: func(a *queryalternatives.Alternatives) bool {
+				_, ok := a.Selected()
+				return ok
+			},
+		},
+		{
+			name: "SelectedPath",
+			resolved: func(a *queryalternatives.Alternatives) bool {
+				_, ok := a.SelectedPath()
+				return ok
+			},
+		},
+		{
+			name: "PriorityGap",
+			resolved: func(a *queryalternatives.Alternatives) bool {
+				_, ok := a.PriorityGap()
+				return ok
+			},
+		},
+		{
+			name: "SetCommand",
+			resolved: func(a *queryalternatives.Alternatives) bool {
+				_, ok := a.SetCommand()
+				return ok
+			},
+		},
+		{
+			name: "AsSelection",
+			resolved: func(a *queryalternatives.Alternatives) bool {
+				return a.AsSelection().Current != ""
+			},
+		},
+		{
+			name: "BrokenSlaveLinks",
+			resolved: func(a *queryalternatives.Alternatives) bool {
+				_, err := a.BrokenSlaveLinks(fakeReadlink(nil))
+				return err == nil
+			},
+		},
+		{
+			name: "GroupBySelectedPath",
+			resolved: func(a *queryalternatives.Alternatives) bool {
+				return len(queryalternatives.GroupBySelectedPath([]*queryalternatives.Alternatives{a})) > 0
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			assert.True(t, test.resolved(newEditor()))
+
+			for _, value := range []string{"none", ""} {
+				a := newEditor()
+				a.Value = value
+				// An alternative which is literally called "none" must never
+				// be looked up.
+				a.Alternatives = append(a.Alternatives, queryalternatives.Alternative{Path: "none", Priority: 60})
+				assert.False(t, test.resolved(a), "value %q", value)
+			}
+		})
+	}
+
+	a := newEditor()
+	best, ok := a.BestAlternative()
+	if assert.True(t, ok) {
+		assert.Equal(t, "/usr/bin/vim.basic", best.Path)
+	}
+	a.Best = ""
+	_, ok = a.BestAlternative()
+	assert.False(t, ok)
+}
+
+func Test_DriftsFromBest(t *testing.T) {
+	t.Parallel()
+
+	drifted := newJava()
+	drifted.Value = "/usr/lib/jvm/java-8-openjdk-amd64/jre/bin/java"
+	empty := newJava()
+	empty.Best = ""
+	empty.Value = "none"
+	empty.Alternatives = nil
+	noBest := newJava()
+	noBest.Best = ""
+
+	tests := []struct {
+		name     string
+		input    *queryalternatives.Alternatives
+		expected bool
+	}{
+		{name: "auto on best", input: newJava(), expected: false},
+		{name: "auto drifted", input: drifted, expected: true},
+		{name: "manual", input: newEditor(), expected: false},
+		{name: "no alternatives", input: empty, expected: false},
+		{name: "selection without best", input: noBest, expected: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			assert.Equal(t, test.expected, test.input.DriftsFromBest())
+		})
+	}
+}
+
+func Test_AffectedByRemoval(t *testing.T) {
+	t.Parallel()
+
+	java21 := "/usr/lib/jvm/java-21-openjdk-amd64/bin/java"
+	java8 := "/usr/lib/jvm/java-8-openjdk-amd64/jre/bin/java"
+
+	tests := []struct {
+		name      string
+		paths     []string
+		willBreak bool
+		affected  []string
+	}{
+		{name: "selected directory", paths: []string{"/usr/lib/jvm/java-21-openjdk-amd64"}, willBreak: true, affected: []string{java21}},
+		{name: "trailing slash", paths: []string{"/usr/lib/jvm/java-21-openjdk-amd64/"}, willBreak: true, affected: []string{java21}},
+		{name: "non-selected directory", paths: []string{"/usr/lib/jvm/java-8-openjdk-amd64"}, willBreak: false, affected: []string{java8}},
+		{name: "exact path", paths: []string{java8}, willBreak: false, affected: []string{java8}},
+		{name: "both", paths: []string{"/usr/lib/jvm"}, willBreak: true, affected: []string{java21, java8}},
+		{name: "sibling with common prefix", paths: []string{"/usr/lib/jvm/java-21"}, willBreak: false, affected: []string{}},
+		{name: "nothing", paths: nil, willBreak: false, affected: []string{}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			willBreak, affected := newJava().AffectedByRemoval(test.paths)
+			assert.Equal(t, test.willBreak, willBreak)
+			paths := make([]string, 0)
+			for _, alt := range affected {
+				paths = append(paths, alt.Path)
+			}
+			assert.Equal(t, test.affected, paths)
+		})
+	}
+
+	none := newJava()
+	none.Value = "none"
+	willBreak, affected := none.AffectedByRemoval([]string{"/usr/lib/jvm"})
+	assert.False(t, willBreak)
+	assert.Len(t, affected, 2)
+}
+
+func Test_SelectionState(t *testing.T) {
+	t.Parallel()
+
+	java8 := "/usr/lib/jvm/java-8-openjdk-amd64/jre/bin/java"
+
+	tests := []struct {
+		name     string
+		modify   func(a *queryalternatives.Alternatives)
+		expected queryalternatives.SelectionState
+		wantErr  bool
+	}{
+		{name: "auto best", modify: func(*queryalternatives.Alternatives) {}, expected: queryalternatives.StateAutoBest},
+		{
+			name: "auto best without Best",
+			modify: func(a *queryalternatives.Alternatives) {
+				a.Best = ""
+			},
+			expected: queryalternatives.StateAutoBest,
+		},
+		{
+			name: "auto drift",
+			modify: func(a *queryalternatives.Alternatives) {
+				a.Value = java8
+			},
+			expected: queryalternatives.StateAutoDrift,
+		},
+		{
+			name: "manual best",
+			modify: func(a *queryalternatives.Alternatives) {
+				a.Status = queryalternatives.StatusManual
+			},
+			expected: queryalternatives.StateManualBest,
+		},
+		{
+			name: "manual below best",
+			modify: func(a *queryalternatives.Alternatives) {
+				a.Status = queryalternatives.StatusManual
+				a.Value = java8
+			},
+			expected: queryalternatives.StateManualBelowBest,
+		},
+		{
+			name: "manual with the same priority as best",
+			modify: func(a *queryalternatives.Alternatives) {
+				a.Status = queryalternatives.StatusManual
+				a.Value = java8
+				a.Alternatives[1].Priority = a.Alternatives[0].Priority
+			},
+			expected: queryalternatives.StateManualBelowBest,
+		},
+		{
+			name: "manual above best",
+			modify: func(a *queryalternatives.Alternatives) {
+				a.Status = queryalternatives.StatusManual
+				a.Value = java8
+				a.Alternatives[1].Priority = 3000
+			},
+			expected: queryalternatives.StateManualAboveBest,
+		},
+		{
+			name: "none",
+			modify: func(a *queryalternatives.Alternatives) {
+				a.Value = "none"
+			},
+			expected: queryalternatives.StateNone,
+		},
+		{
+			name: "empty value in manual mode",
+			modify: func(a *queryalternatives.Alternatives) {
+				a.Status = queryalternatives.StatusManual
+				a.Value = ""
+			},
+			expected: queryalternatives.StateNone,
+		},
+		{
+			name: "dangling",
+			modify: func(a *queryalternatives.Alternatives) {
+				a.Status = queryalternatives.StatusManual
+				a.Value = "/opt/java/bin/java"
+			},
+			expected: queryalternatives.StateDangling,
+		},
+		{
+			name: "invalid status",
+			modify: func(a *queryalternatives.Alternatives) {
+				a.Status = "pinned"
+			},
+			wantErr: true,
+		},
+		{
+			name: "unregistered best in manual mode",
+			modify: func(a *queryalternatives.Alternatives) {
+				a.Status = queryalternatives.StatusManual
+				a.Best = "/opt/java/bin/java"
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			a := newJava()
+			test.modify(a)
+			result, err := a.SelectionState()
+			if test.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, test.expected, result)
+		})
+	}
+
+	assert.Equal(t, "manual-below-best", queryalternatives.StateManualBelowBest.String())
+	assert.Equal(t, "SelectionState(42)", queryalternatives.SelectionState(42).String())
+}
+
+func Test_CandidateRows(t *testing.T) {
+	t.Parallel()
+
+	assert.Equal(t, []queryalternatives.CandidateRow{
+		{Path: "/usr/lib/jvm/java-21-openjdk-amd64/bin/java", Priority: 2111, Selected: true, Best: true},
+		{Path: "/usr/lib/jvm/java-8-openjdk-amd64/jre/bin/java", Priority: 1081},
+	}, newJava().CandidateRows())
+
+	assert.Equal(t, []queryalternatives.CandidateRow{
+		{Path: "/bin/nano", Priority: 40, Selected: true},
+		{Path: "/usr/bin/vim.basic", Priority: 50, Best: true},
+	}, newEditor().CandidateRows())
+
+	noBest := newEditor()
+	noBest.Best = ""
+	noBest.Value = "none"
+	assert.Equal(t, []queryalternatives.CandidateRow{
+		{Path: "/bin/nano", Priority: 40},
+		{Path: "/usr/bin/vim.basic", Priority: 50, Best: true},
+	}, noBest.CandidateRows())
+
+	empty := &queryalternatives.Alternatives{Name: "pager", Value: "none"}
+	assert.Equal(t, []queryalternatives.CandidateRow{}, empty.CandidateRows())
+}
@@ -0,0 +1,137 @@
+package queryalternatives
+
+import (
+	"maps"
+	"sort"
+)
+
+// SlaveLink is a slave link and its corresponding path.
+type SlaveLink struct {
+	// Link is the name of the slave link.
+	// For example, "java.1.gz".
+	Link string
+	// Path is the path associated with the slave link.
+	Path string
+}
+
+// slaveLinks converts slaves to a slice sorted by link name.
+func slaveLinks(slaves map[string]string) []SlaveLink {
+	result := make([]SlaveLink, 0, len(slaves))
+	for link, path := range slaves {
+		result = append(result, SlaveLink{Link: link, Path: path})
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Link < result[j].Link
+	})
+	return result
+}
+
+// GroupSlaveTargets returns the group-level slaves sorted by link name.
+func (a *Alternatives) GroupSlaveTargets() []SlaveLink {
+	return slaveLinks(a.Slaves)
+}
+
+// SelectedSlaves returns a copy of the slaves of the currently selected
+// alternative, keyed by link name.
+// The second result is false if no alternative is selected or Value is not
+// a registered alternative.
+func (a *Alternatives) SelectedSlaves() (map[string]string, bool) {
+	alt := a.selected()
+	if alt == nil {
+		return nil, false
+	}
+	return maps.Clone(alt.Slaves), true
+}
+
+// SelectedSlaveLinks is like SelectedSlaves, but returns the slaves sorted by
+// link name. Together with GroupSlaveTargets, it gives the link and the
+// target of each slave symlink.
+func (a *Alternatives) SelectedSlaveLinks() ([]SlaveLink, bool) {
+	alt := a.selected()
+	if alt == nil {
+		return nil, false
+	}
+	return slaveLinks(alt.Slaves), true
+}
+
+// UndeclaredSlaveLinks returns the sorted names of slave links which appear
+// in some alternative but are not declared in the group-level Slaves.
+func (a *Alternatives) UndeclaredSlaveLinks() []string {
+	seen := make(map[string]struct{})
+	for _, alt := range a.Alternatives {
+		for link := range alt.Slaves {
+			if _, ok := a.Slaves[link]; !ok {
+				seen[link] = struct{}{}
+			}
+		}
+	}
+
+	result := make([]string, 0, len(seen))
+	for link := range seen {
+		result = append(result, link)
+	}
+	sort.Strings(result)
+	return result
+}
+
+// AlternativesWithSlave returns the alternatives which provide the slave
+// link, keeping their order.
+func (a *Alternatives) AlternativesWithSlave(link string) []Alternative {
+	result := make([]Alternative, 0)
+	for _, alt := range a.Alternatives {
+		if _, ok := alt.Slaves[link]; ok {
+			result = append(result, alt)
+		}
+	}
+	return result
+}
+
+// SlaveSchemaMismatches returns a map from the path of each alternative
+// whose slaves differ from the group-level Slaves to the sorted names of the
+// differing slaves: those declared by the group but not provided by the
+// alternative, and those provided by the alternative but not declared by
+// the group. Alternatives which match the declaration are omitted.
+func (a *Alternatives) SlaveSchemaMismatches() map[string][]string {
+	result := make(map[string][]string)
+	for _, alt := range a.Alternatives {
+		var names []string
+		for link := range a.Slaves {
+			if _, ok := alt.Slaves[link]; !ok {
+				names = append(names, link)
+			}
+		}
+		for link := range alt.Slaves {
+			if _, ok := a.Slaves[link]; !ok {
+				names = append(names, link)
+			}
+		}
+		if len(names) > 0 {
+			sort.Strings(names)
+			result[alt.Path] = names
+		}
+	}
+	return result
+}
+
+// CandidatesMissingGroupSlaves returns a map from the path of each
+// alternative which does not provide some of the slaves declared by the
+// group to the sorted names of those slaves. Their links would dangle after
+// switching to such an alternative. Alternatives which provide all declared
+// slaves are omitted. Unlike SlaveSchemaMismatches, slaves which are not
+// declared by the group are ignored.
+func (a *Alternatives) CandidatesMissingGroupSlaves() map[string][]string {
+	declared := a.GroupSlaveTargets()
+	result := make(map[string][]string)
+	for _, alt := range a.Alternatives {
+		var names []string
+		for _, slave := range declared {
+			if _, ok := alt.Slaves[slave.Link]; !ok {
+				names = append(names, slave.Link)
+			}
+		}
+		if len(names) > 0 {
+			result[alt.Path] = names
+		}
+	}
+	return result
+}
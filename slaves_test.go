@@ -0,0 +1,143 @@
+package queryalternatives_test
+
+import (
+	"testing"
+
+	"github.com/kofuk/go-queryalternatives"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_GroupSlaveTargets(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		slaves   map[string]string
+		expected []queryalternatives.SlaveLink
+	}{
+		{
+			name: "sorted by link",
+			slaves: map[string]string{
+				"jexec":     "/usr/bin/jexec",
+				"java.1.gz": "/usr/share/man/man1/java.1.gz",
+			},
+			expected: []queryalternatives.SlaveLink{
+				{Link: "java.1.gz", Path: "/usr/share/man/man1/java.1.gz"},
+				{Link: "jexec", Path: "/usr/bin/jexec"},
+			},
+		},
+		{
+			name:     "nil map",
+			slaves:   nil,
+			expected: []queryalternatives.SlaveLink{},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			a := &queryalternatives.Alternatives{Slaves: test.slaves}
+			assert.Equal(t, test.expected, a.GroupSlaveTargets())
+		})
+	}
+}
+
+func Test_SelectedSlaveLinks(t *testing.T) {
+	t.Parallel()
+
+	a := newJava()
+	a.Slaves["jexec"] = "/usr/bin/jexec"
+	a.Slaves["java.ja.1.gz"] = "/usr/share/man/ja/man1/java.1.gz"
+	a.Alternatives[0].Slaves["jexec"] = "/usr/lib/jvm/java-21-openjdk-amd64/lib/jexec"
+	a.Alternatives[0].Slaves["java.ja.1.gz"] = "/usr/lib/jvm/java-21-openjdk-amd64/man/ja/man1/java.1.gz"
+
+	result, ok := a.SelectedSlaveLinks()
+	assert.True(t, ok)
+	assert.Equal(t, []queryalternatives.SlaveLink{
+		{Link: "java.1.gz", Path: "/usr/lib/jvm/java-21-openjdk-amd64/man/man1/java.1.gz"},
+		{Link: "java.ja.1.gz", Path: "/usr/lib/jvm/java-21-openjdk-amd64/man/ja/man1/java.1.gz"},
+		{Link: "jexec", Path: "/usr/lib/jvm/java-21-openjdk-amd64/lib/jexec"},
+	}, result)
+
+	slaves, ok := a.SelectedSlaves()
+	assert.True(t, ok)
+	assert.Equal(t, a.Alternatives[0].Slaves, slaves)
+	slaves["jexec"] = "/tmp/jexec"
+	assert.Equal(t, "/usr/lib/jvm/java-21-openjdk-amd64/lib/jexec", a.Alternatives[0].Slaves["jexec"])
+
+	a.Value = "none"
+	result, ok = a.SelectedSlaveLinks()
+	assert.False(t, ok)
+	assert.Nil(t, result)
+	_, ok = a.SelectedSlaves()
+	assert.False(t, ok)
+}
+
+func Test_UndeclaredSlaveLinks(t *testing.T) {
+	t.Parallel()
+
+	a := newJava()
+	assert.Empty(t, a.UndeclaredSlaveLinks())
+
+	a.Alternatives[0].Slaves["jexec"] = "/usr/lib/jvm/java-21-openjdk-amd64/lib/jexec"
+	a.Alternatives[1].Slaves["jexec"] = "/usr/lib/jvm/java-8-openjdk-amd64/jre/lib/jexec"
+	a.Alternatives[1].Slaves["java.ja.1.gz"] = "/usr/lib/jvm/java-8-openjdk-amd64/jre/man/ja/man1/java.1.gz"
+	assert.Equal(t, []string{"java.ja.1.gz", "jexec"}, a.UndeclaredSlaveLinks())
+
+	a.Slaves = nil
+	assert.Equal(t, []string{"java.1.gz", "java.ja.1.gz", "jexec"}, a.UndeclaredSlaveLinks())
+}
+
+func Test_AlternativesWithSlave(t *testing.T) {
+	t.Parallel()
+
+	a := newJava()
+	a.Alternatives[0].Slaves["java.ja.1.gz"] = "/usr/lib/jvm/java-21-openjdk-amd64/man/ja/man1/java.1.gz"
+	a.Alternatives = append(a.Alternatives, queryalternatives.Alternative{
+		Path:     "/usr/lib/jvm/java-17-openjdk-amd64/bin/java",
+		Priority: 1711,
+	})
+
+	assert.Equal(t, []queryalternatives.Alternative{a.Alternatives[0], a.Alternatives[1]}, a.AlternativesWithSlave("java.1.gz"))
+	assert.Equal(t, []queryalternatives.Alternative{a.Alternatives[0]}, a.AlternativesWithSlave("java.ja.1.gz"))
+	assert.Equal(t, []queryalternatives.Alternative{}, a.AlternativesWithSlave("jexec"))
+}
+
+func Test_SlaveSchemaMismatches(t *testing.T) {
+	t.Parallel()
+
+	a := newJava()
+	assert.Empty(t, a.SlaveSchemaMismatches())
+
+	a.Slaves["jexec"] = "/usr/bin/jexec"
+	a.Alternatives[0].Slaves["jexec"] = "/usr/lib/jvm/java-21-openjdk-amd64/lib/jexec"
+	a.Alternatives[1].Slaves["java.ja.1.gz"] = "/usr/lib/jvm/java-8-openjdk-amd64/jre/man/ja/man1/java.1.gz"
+	a.Alternatives = append(a.Alternatives, queryalternatives.Alternative{
+		Path:     "/usr/lib/jvm/java-17-openjdk-amd64/bin/java",
+		Priority: 1711,
+	})
+
+	assert.Equal(t, map[string][]string{
+		"/usr/lib/jvm/java-8-openjdk-amd64/jre/bin/java": {"java.ja.1.gz", "jexec"},
+		"/usr/lib/jvm/java-17-openjdk-amd64/bin/java":    {"java.1.gz", "jexec"},
+	}, a.SlaveSchemaMismatches())
+}
+
+func Test_CandidatesMissingGroupSlaves(t *testing.T) {
+	t.Parallel()
+
+	a := newJava()
+	assert.Empty(t, a.CandidatesMissingGroupSlaves())
+
+	a.Slaves["jexec"] = "/usr/bin/jexec"
+	a.Slaves["java.ja.1.gz"] = "/usr/share/man/ja/man1/java.1.gz"
+	a.Alternatives[0].Slaves["jexec"] = "/usr/lib/jvm/java-21-openjdk-amd64/lib/jexec"
+	a.Alternatives[0].Slaves["java.ja.1.gz"] = "/usr/lib/jvm/java-21-openjdk-amd64/man/ja/man1/java.1.gz"
+	// An undeclared slave does not count.
+	a.Alternatives[1].Slaves["keytool.1.gz"] = "/usr/lib/jvm/java-8-openjdk-amd64/jre/man/man1/keytool.1.gz"
+
+	assert.Equal(t, map[string][]string{
+		"/usr/lib/jvm/java-8-openjdk-amd64/jre/bin/java": {"java.ja.1.gz", "jexec"},
+	}, a.CandidatesMissingGroupSlaves())
+}
@@ -0,0 +1,157 @@
+package queryalternatives
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+)
+
+// Severity is the severity of an Issue.
+type Severity int
+
+const (
+	// SeverityWarning is for unusual but valid states.
+	SeverityWarning Severity = iota
+	// SeverityError is for inconsistent states.
+	SeverityError
+)
+
+func (s Severity) String() string {
+	switch s {
+	case SeverityWarning:
+		return "warning"
+	case SeverityError:
+		return "error"
+	}
+	return fmt.Sprintf("Severity(%d)", int(s))
+}
+
+// Issue is a problem found by Validate.
+type Issue struct {
+	Severity Severity
+	Message  string
+}
+
+func (i Issue) String() string {
+	return i.Severity.String() + ": " + i.Message
+}
+
+// IsEmpty reports whether the group has no alternatives.
+// This is legitimate, for example after all candidates were removed, in
+// which case Value is "none".
+func (a *Alternatives) IsEmpty() bool {
+	return len(a.Alternatives) == 0
+}
+
+// DuplicatePaths returns a map from each path which appears more than once
+// in Alternatives to the priorities of its entries, in order.
+// update-alternatives never registers a path twice, so this indicates a
+// corrupted database.
+func (a *Alternatives) DuplicatePaths() map[string][]int {
+	priorities := make(map[string][]int)
+	for _, alt := range a.Alternatives {
+		priorities[alt.Path] = append(priorities[alt.Path], alt.Priority)
+	}
+
+	result := make(map[string][]int)
+	for path, p := range priorities {
+		if len(p) > 1 {
+			result[path] = p
+		}
+	}
+	return result
+}
+
+// Sanitize removes leading and trailing whitespace from Name, Link, Best,
+// Value, the paths of the alternatives and the names and paths of all
+// slaves, which may be left by tools writing the output of
+// update-alternatives sloppily.
+func (a *Alternatives) Sanitize() {
+	a.sanitize(false)
+}
+
+// sanitize implements Sanitize and reports whether anything was, or with
+// dryRun would be, changed. If dryRun is true, a is left unmodified.
+func (a *Alternatives) sanitize(dryRun bool) bool {
+	changed := false
+	trim := func(s *string) {
+		if t := strings.TrimSpace(*s); t != *s {
+			changed = true
+			if !dryRun {
+				*s = t
+			}
+		}
+	}
+	trimSlaves := func(slaves map[string]string) map[string]string {
+		result := make(map[string]string, len(slaves))
+		for link, path := range slaves {
+			trim(&link)
+			trim(&path)
+			result[link] = path
+		}
+		if dryRun {
+			return slaves
+		}
+		return result
+	}
+
+	trim(&a.Name)
+	trim(&a.Link)
+	trim(&a.Best)
+	trim(&a.Value)
+	if a.Slaves != nil {
+		a.Slaves = trimSlaves(a.Slaves)
+	}
+	for i := range a.Alternatives {
+		trim(&a.Alternatives[i].Path)
+		if a.Alternatives[i].Slaves != nil {
+			a.Alternatives[i].Slaves = trimSlaves(a.Alternatives[i].Slaves)
+		}
+	}
+	return changed
+}
+
+// Validate checks the consistency of the group and returns the issues found.
+// It returns an empty slice if there is nothing to report.
+func (a *Alternatives) Validate() []Issue {
+	issues := make([]Issue, 0)
+	errorf := func(format string, args ...any) {
+		issues = append(issues, Issue{Severity: SeverityError, Message: fmt.Sprintf(format, args...)})
+	}
+	warnf := func(format string, args ...any) {
+		issues = append(issues, Issue{Severity: SeverityWarning, Message: fmt.Sprintf(format, args...)})
+	}
+
+	if a.Name == "" {
+		errorf("missing name")
+	}
+	if a.Link == "" {
+		errorf("missing link")
+	}
+	switch a.Status {
+	case StatusAuto, StatusManual, "":
+	case StatusUnknown:
+		errorf("unknown status %q", a.StatusRaw)
+	default:
+		errorf("unknown status %q", a.Status)
+	}
+	if a.IsEmpty() {
+		warnf("no alternatives")
+	}
+	if a.Best != "" && !a.hasAlternative(a.Best) {
+		errorf("best alternative %s is not registered", a.Best)
+	}
+	if !isNone(a.Value) && !a.hasAlternative(a.Value) {
+		errorf("selected alternative %s is not registered", a.Value)
+	}
+	if a.sanitize(true) {
+		warnf("fields have leading or trailing whitespace")
+	}
+	duplicates := a.DuplicatePaths()
+	for _, path := range slices.Sorted(maps.Keys(duplicates)) {
+		errorf("alternative %s is registered %d times", path, len(duplicates[path]))
+	}
+
+	return issues
+}
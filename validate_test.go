@@ -0,0 +1,127 @@
+package queryalternatives_test
+
+import (
+	"testing"
+
+	"github.com/kofuk/go-queryalternatives"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_IsEmpty(t *testing.T) {
+	t.Parallel()
+
+	a, err := queryalternatives.ParseString(`Name: java
+Link: /usr/bin/java
+Status: auto
+Value: none
+`)
+	require.NoError(t, err)
+
+	assert.True(t, a.IsEmpty())
+	assert.Equal(t, []queryalternatives.Issue{
+		{Severity: queryalternatives.SeverityWarning, Message: "no alternatives"},
+	}, a.Validate())
+
+	assert.False(t, newJava().IsEmpty())
+}
+
+func Test_Validate(t *testing.T) {
+	t.Parallel()
+
+	assert.Empty(t, newJava().Validate())
+	assert.Empty(t, newEditor().Validate())
+
+	a := newJava()
+	a.Name = ""
+	a.Best = "/usr/lib/jvm/java-17-openjdk-amd64/bin/java"
+	a.Value = "/usr/lib/jvm/java-11-openjdk-amd64/bin/java"
+	assert.Equal(t, []queryalternatives.Issue{
+		{Severity: queryalternatives.SeverityError, Message: "missing name"},
+		{Severity: queryalternatives.SeverityError, Message: "best alternative /usr/lib/jvm/java-17-openjdk-amd64/bin/java is not registered"},
+		{Severity: queryalternatives.SeverityError, Message: "selected alternative /usr/lib/jvm/java-11-openjdk-amd64/bin/java is not registered"},
+	}, a.Validate())
+
+	assert.Equal(t, "error: missing name", a.Validate()[0].String())
+}
+
+func Test_Validate_UnknownStatus(t *testing.T) {
+	t.Parallel()
+
+	a := newJava()
+	a.Status = queryalternatives.StatusUnknown
+	a.StatusRaw = "broken"
+	assert.Equal(t, []queryalternatives.Issue{
+		{Severity: queryalternatives.SeverityError, Message: `unknown status "broken"`},
+	}, a.Validate())
+
+	a.Status = "pinned"
+	a.StatusRaw = ""
+	assert.Equal(t, []queryalternatives.Issue{
+		{Severity: queryalternatives.SeverityError, Message: `unknown status "pinned"`},
+	}, a.Validate())
+}
+
+func Test_DuplicatePaths(t *testing.T) {
+	t.Parallel()
+
+	assert.Empty(t, newJava().DuplicatePaths())
+
+	a := newEditor()
+	a.Alternatives = append(a.Alternatives,
+		queryalternatives.Alternative{Path: "/bin/nano", Priority: 45},
+		queryalternatives.Alternative{Path: "/usr/bin/vim.basic", Priority: 50},
+		queryalternatives.Alternative{Path: "/bin/nano", Priority: 40},
+	)
+	assert.Equal(t, map[string][]int{
+		"/bin/nano":          {40, 45, 40},
+		"/usr/bin/vim.basic": {50, 50},
+	}, a.DuplicatePaths())
+
+	assert.Equal(t, []queryalternatives.Issue{
+		{Severity: queryalternatives.SeverityError, Message: "alternative /bin/nano is registered 3 times"},
+		{Severity: queryalternatives.SeverityError, Message: "alternative /usr/bin/vim.basic is registered 2 times"},
+	}, a.Validate())
+}
+
+func Test_Sanitize(t *testing.T) {
+	t.Parallel()
+
+	a := &queryalternatives.Alternatives{
+		Name: " java",
+		Link: "/usr/bin/java\t",
+		Slaves: map[string]string{
+			"java.1.gz ": " /usr/share/man/man1/java.1.gz",
+		},
+		Status: queryalternatives.StatusAuto,
+		Best:   "/usr/lib/jvm/java-21-openjdk-amd64/bin/java ",
+		Value:  "  /usr/lib/jvm/java-21-openjdk-amd64/bin/java",
+		Alternatives: []queryalternatives.Alternative{
+			{
+				Path:     "/usr/lib/jvm/java-21-openjdk-amd64/bin/java\r",
+				Priority: 2111,
+				Slaves: map[string]string{
+					"java.1.gz": "/usr/lib/jvm/java-21-openjdk-amd64/man/man1/java.1.gz ",
+				},
+			},
+			{
+				Path:     "/usr/lib/jvm/java-8-openjdk-amd64/jre/bin/java",
+				Priority: 1081,
+				Slaves: map[string]string{
+					" java.1.gz": "/usr/lib/jvm/java-8-openjdk-amd64/jre/man/man1/java.1.gz",
+				},
+			},
+		},
+	}
+
+	assert.Contains(t, a.Validate(), queryalternatives.Issue{
+		Severity: queryalternatives.SeverityWarning,
+		Message:  "fields have leading or trailing whitespace",
+	})
+	// Validate does not modify the group.
+	assert.Equal(t, " java", a.Name)
+
+	a.Sanitize()
+	assert.Equal(t, newJava(), a)
+	assert.Empty(t, a.Validate())
+}